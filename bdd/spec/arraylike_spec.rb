@@ -30,10 +30,9 @@ describe 'database' do
       ".exit",
     ])
     expect(result).to match_array([
-      "db > Executed.",
-      "db > (1, user1, person1@example.com)",
       "Executed.",
-      "db > ",
+      "(1, user1, person1@example.com)",
+      "Executed.",
     ])
   end
 
@@ -43,7 +42,7 @@ describe 'database' do
     end
     script << ".exit"
     result = run_script(script)
-    expect(result[-2]).to eq('db > Error: Table full.')
+    expect(result.last).to eq('Error: Table full.')
   end
 
   it 'allows inserting strings that are the maximum length' do
@@ -56,10 +55,9 @@ describe 'database' do
     ]
     result = run_script(script)
     expect(result).to match_array([
-      "db > Executed.",
-      "db > (1, #{long_username}, #{long_email})",
       "Executed.",
-      "db > ",
+      "(1, #{long_username}, #{long_email})",
+      "Executed.",
     ])
   end
 
@@ -73,9 +71,8 @@ describe 'database' do
     ]
     result = run_script(script)
     expect(result).to match_array([
-      "db > String is too long.",
-      "db > Executed.",
-      "db > ",
+      "String is too long.",
+      "Executed.",
     ])
   end
 
@@ -87,9 +84,8 @@ describe 'database' do
     ]
     result = run_script(script)
     expect(result).to match_array([
-      "db > Error on executing `insert -1 cstack foo@bar.com`.",
-      "db > Executed.",
-      "db > ",
+      "ID must be positive.",
+      "Executed.",
     ])
   end
 
@@ -99,17 +95,15 @@ describe 'database' do
       ".exit",
     ])
     expect(result1).to match_array([
-      "db > Executed.",
-      "db > ",
+      "Executed.",
     ])
     result2 = run_script([
       "select",
       ".exit",
     ])
     expect(result2).to match_array([
-      "db > (1, user1, person1@example.com)",
+      "(1, user1, person1@example.com)",
       "Executed.",
-      "db > ",
     ])
   end
 
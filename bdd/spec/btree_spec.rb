@@ -30,10 +30,9 @@ describe 'database' do
       ".exit",
     ])
     expect(result).to match_array([
-      "db > Executed.",
-      "db > (1, user1, person1@example.com)",
       "Executed.",
-      "db > ",
+      "(1, user1, person1@example.com)",
+      "Executed.",
     ])
   end
 
@@ -47,10 +46,9 @@ describe 'database' do
     ]
     result = run_script(script)
     expect(result).to match_array([
-      "db > Executed.",
-      "db > (1, #{long_username}, #{long_email})",
       "Executed.",
-      "db > ",
+      "(1, #{long_username}, #{long_email})",
+      "Executed.",
     ])
   end
 
@@ -64,9 +62,8 @@ describe 'database' do
     ]
     result = run_script(script)
     expect(result).to match_array([
-      "db > String is too long.",
-      "db > Executed.",
-      "db > ",
+      "String is too long.",
+      "Executed.",
     ])
   end
 
@@ -78,9 +75,8 @@ describe 'database' do
     ]
     result = run_script(script)
     expect(result).to match_array([
-      "db > Error on executing `insert -1 cstack foo@bar.com`.",
-      "db > Executed.",
-      "db > ",
+      "ID must be positive.",
+      "Executed.",
     ])
   end
 
@@ -90,17 +86,15 @@ describe 'database' do
       ".exit",
     ])
     expect(result1).to match_array([
-      "db > Executed.",
-      "db > ",
+      "Executed.",
     ])
     result2 = run_script([
       "select",
       ".exit",
     ])
     expect(result2).to match_array([
-      "db > (1, user1, person1@example.com)",
+      "(1, user1, person1@example.com)",
       "Executed.",
-      "db > ",
     ])
   end
 
@@ -112,14 +106,13 @@ describe 'database' do
     result = run_script(script)
 
     expect(result).to match_array([
-      "db > Constants:",
-      "ROW_SIZE: 514",
+      "Constants:",
+      "ROW_SIZE: 525",
       "COMMON_NODE_HEADER_SIZE: 6",
       "LEAF_NODE_HEADER_SIZE: 10",
-      "LEAF_NODE_CELL_SIZE: 518",
+      "LEAF_NODE_CELL_SIZE: 529",
       "LEAF_NODE_SPACE_FOR_CELLS: 4086",
       "LEAF_NODE_MAX_CELLS: 7",
-      "db > ",
     ])
   end
 
@@ -132,15 +125,14 @@ describe 'database' do
     result = run_script(script)
 
     expect(result).to match_array([
-      "db > Executed.",
-      "db > Executed.",
-      "db > Executed.",
-      "db > Tree:",
+      "Executed.",
+      "Executed.",
+      "Executed.",
+      "Tree:",
         "- leaf (size 3)",
         "  - 1",
         "  - 2",
         "  - 3",
-      "db > "
     ])
   end
 
@@ -153,11 +145,10 @@ describe 'database' do
     ]
     result = run_script(script)
     expect(result).to match_array([
-      "db > Executed.",
-      "db > Error: Duplicate key.",
-      "db > (1, user1, person1@example.com)",
       "Executed.",
-      "db > ",
+      "Error: Duplicate key.",
+      "(1, user1, person1@example.com)",
+      "Executed.",
     ])
   end
 
@@ -170,7 +161,7 @@ describe 'database' do
     script << ".exit"
     result = run_script(script)
      expect(result[8...(result.length)]).to match_array([
-      "db > Tree:",
+      "Tree:",
       "- internal (size 1)",
       "  - leaf (size 4)",
       "    - 1",
@@ -183,7 +174,7 @@ describe 'database' do
       "    - 6",
       "    - 7",
       "    - 8",
-      "db > Need to implement searching an internal node",
+      "Need to implement searching an internal node",
     ])
   end
 end
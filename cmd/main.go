@@ -2,48 +2,80 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/meysampg/sqltut/engine"
-	"github.com/meysampg/sqltut/engine/storage/arraylike"
-	"github.com/meysampg/sqltut/engine/storage/btree"
+	"github.com/meysampg/sqltut/engine/output"
+	_ "github.com/meysampg/sqltut/engine/storage/arraylike"
+	_ "github.com/meysampg/sqltut/engine/storage/btree"
+	"github.com/meysampg/sqltut/server"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
 var (
-	dbPath   string
-	dbEngine string
-	cli      string
+	dbPath      string
+	dbEngine    string
+	cli         string
+	command     string
+	scriptPath  string
+	readOnly    bool
+	busyTimeout time.Duration
+	useMmap     bool
+	connect     string
 )
 
 func init() {
-	flag.StringVar(&dbPath, "db-path", "./db", "Path of the DB file")
-	flag.StringVar(&dbEngine, "engine", "arraylike", "Engine to store and query")
+	flag.StringVar(&dbPath, "db-path", "./db", "Path of the DB file (:memory: keeps the DB in memory)")
+	flag.StringVar(&dbEngine, "engine", engine.DefaultEngine, "Engine to store and query (list prints the available ones)")
 	flag.StringVar(&cli, "cli", "cli", "CLI to use (cli and complete)")
+	flag.StringVar(&command, "c", "", "Execute the given statement and exit")
+	flag.StringVar(&scriptPath, "f", "", "Execute statements of the given file and exit")
+	flag.StringVar(&outputMode, "mode", string(output.ModeTuple), "Output mode of results (tuple, table, csv, json, jsonl and line)")
+	flag.BoolVar(&outputHeaders, "headers", false, "Print column names of results")
+	flag.BoolVar(&readOnly, "readonly", false, "Open the DB file as read only, sharing it with other readers")
+	flag.DurationVar(&busyTimeout, "busy-timeout", 0, "How long to wait for a DB file which is locked by another process")
+	flag.BoolVar(&useMmap, "mmap", false, "Read the DB file through a memory mapping (on btree engine)")
+	flag.StringVar(&connect, "connect", "", "Address of a server (see `serve`) to run statements on instead of the DB file")
+}
 
-	flag.Parse()
+// listEngines prints the registered engines and their capabilities.
+func listEngines() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tRANGES\tTRANSACTIONS\tMETA COMMANDS\tDESCRIPTION")
+	for _, info := range engine.Engines() {
+		c := info.Capabilities
+		meta := strings.Join(c.MetaCommands, " ")
+		if meta == "" {
+			meta = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", info.Name, yesNo(c.Ranges), yesNo(c.Transactions), meta, info.Description)
+	}
+	w.Flush()
 }
 
-func getEngine(typ, path string) (engine.Storage, error) {
-	switch typ {
-	case "arraylike":
-		return arraylike.DbOpen(path)
-	case "btree":
-		return btree.DbOpen(path)
-	default:
-		return nil, fmt.Errorf("Engine not found, %s", typ)
+func yesNo(b bool) string {
+	if b {
+		return "yes"
 	}
+
+	return "no"
 }
 
-func simpleInput(reader *bufio.Reader) ([]byte, error) {
+func simpleInput(reader *bufio.Reader, prompt string) ([]byte, error) {
 	var result []byte
 	var isPrefix bool = true
 
-	fmt.Print("db > ")
+	fmt.Print(prompt)
 	for isPrefix {
 		l, prefix, err := reader.ReadLine()
 		if err != nil {
@@ -56,21 +88,50 @@ func simpleInput(reader *bufio.Reader) ([]byte, error) {
 	return result, nil
 }
 
-func getInput() func() []byte {
-	switch cli {
-	case "complete":
-		return func() []byte {
+// isTerminal reports whether f is attached to a terminal rather than a pipe or
+// a regular file.
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// getInput returns the source of statements, a function which closes it and
+// whether it's an interactive session. Prompts are only printed on interactive
+// sessions.
+func getInput() (func() ([]byte, error), func() error, bool, error) {
+	var source io.Reader = os.Stdin
+	closeInput := func() error { return nil }
+	switch {
+	case command != "":
+		source = strings.NewReader(command)
+	case scriptPath != "":
+		f, err := os.Open(scriptPath)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		source, closeInput = f, f.Close
+	case cli == "complete":
+		return func() ([]byte, error) {
 			l := prompt.Input(">>> ", completer, cliOptions()...)
 			Persist(l)
-			return []byte(l)
-		}
-	default:
-		reader := bufio.NewReader(os.Stdin)
-		return func() []byte {
-			l, _ := simpleInput(reader)
-			return l
-		}
+			return []byte(l), nil
+		}, closeInput, true, nil
 	}
+
+	interactive := source == os.Stdin && isTerminal(os.Stdin)
+	prefix := ""
+	if interactive {
+		prefix = "db > "
+	}
+
+	reader := bufio.NewReader(source)
+	return func() ([]byte, error) {
+		return simpleInput(reader, prefix)
+	}, closeInput, interactive, nil
 }
 
 func cliOptions() []prompt.Option {
@@ -99,46 +160,142 @@ func completer(in prompt.Document) []prompt.Suggest {
 		{Text: "select", Description: "show all stored users"},
 		{Text: ".btree", Description: "show the saved btree (on btree engine)"},
 		{Text: ".constants", Description: "show constants (on btree engine)"},
+		{Text: ".dump", Description: "print the db as a script of statements (.dump [file])"},
+		{Text: ".read", Description: "execute statements of a script file (.read file)"},
+		{Text: ".backup", Description: "copy the live db into another file (.backup file)"},
+		{Text: ".mode", Description: "set output mode (tuple, table, csv, json, jsonl and line)"},
+		{Text: ".headers", Description: "print column names of results (.headers on|off)"},
 		{Text: ".exit", Description: "flush the db and exit"},
 	}
 	return prompt.FilterHasPrefix(s, in.GetWordBeforeCursor(), true)
 }
 
+// report prints the outcome of a statement and returns false if it's failed.
+func report(l []byte, err error) bool {
+	switch {
+	case err == nil:
+		if !bytes.HasPrefix(l, []byte(".")) {
+			fmt.Println("Executed.")
+		}
+		return true
+	case errors.Is(err, engine.ErrTableFull):
+		fmt.Println("Error: Table full.")
+	case errors.Is(err, engine.ErrUnrecognizedCommand):
+		fmt.Printf("Unrecognized command '%s'\n", string(l))
+	case errors.Is(err, engine.ErrCommandFailure):
+		fmt.Println(err)
+		fmt.Printf("Error on executing `%s`.\n", string(l))
+	case errors.Is(err, engine.ErrUnrecognizedStatement):
+		fmt.Printf("Unrecognized keyword at start of '%s'.\n", string(l))
+	case errors.Is(err, engine.ErrSyntax):
+		fmt.Printf("Error on executing `%s`.\n", string(l))
+	case errors.Is(err, engine.ErrStringTooLong):
+		fmt.Println("String is too long.")
+	case errors.Is(err, engine.ErrNegativeId):
+		fmt.Println("ID must be positive.")
+	case errors.Is(err, engine.ErrDuplicateKey):
+		fmt.Println("Error: Duplicate key.")
+	case errors.Is(err, engine.ErrReadOnly):
+		fmt.Println("Error: Database is read only.")
+	case errors.Is(err, engine.ErrNotImplemented):
+		fmt.Println(errors.Unwrap(err))
+		os.Exit(int(engine.TODO))
+	default:
+		fmt.Printf("Error: %s.\n", err)
+	}
+
+	return false
+}
+
 func main() {
-	table, err := getEngine(dbEngine, dbPath)
+	flag.Parse()
+
+	if dbEngine == "list" {
+		listEngines()
+		os.Exit(0)
+	}
+
+	switch flag.Arg(0) {
+	case "migrate":
+		os.Exit(migrate(flag.Args()[1:]))
+	case "serve":
+		os.Exit(serve(flag.Args()[1:]))
+	}
+
+	if err := setOutput(output.Mode(outputMode), outputHeaders); err != nil {
+		fmt.Println(err)
+		os.Exit(int(engine.ExitFailure))
+	}
+
+	var table io.Closer
+	var process func(l []byte) error
+	if connect != "" {
+		client, err := server.Dial(connect, 5*time.Second)
+		if err != nil {
+			fmt.Printf("Unable to connect to %s: %s\n", connect, err)
+			os.Exit(int(engine.ExitFailure))
+		}
+		r := &remote{client: client}
+		table, process = client, r.process
+	} else {
+		storage, err := engine.OpenStorage(dbEngine, dbPath, engine.Options{ReadOnly: readOnly, BusyTimeout: busyTimeout, MMap: useMmap})
+		if errors.Is(err, engine.ErrEngineNotFound) {
+			fmt.Printf("%s (see -engine list)\n", err)
+			os.Exit(int(engine.ExitFailure))
+		} else if errors.Is(err, engine.ErrLocked) {
+			fmt.Println("Error: Database is locked.")
+			os.Exit(int(engine.ExecuteLocked))
+		} else if err != nil {
+			fmt.Println("Unable to open file")
+			os.Exit(int(engine.ExitFailure))
+		}
+		table = storage
+		process = func(l []byte) error {
+			return engine.Process(l, storage, replWriter{})
+		}
+	}
+
+	input, closeInput, interactive, err := getInput()
 	if err != nil {
-		fmt.Println("Unable to open file")
+		fmt.Println(err)
+		table.Close()
 		os.Exit(int(engine.ExitFailure))
 	}
 
-	input := getInput()
+	failed := false
 	for {
-		l := input()
-		switch engine.Process(l, table) {
-		case engine.MetaCommandSuccess:
-			continue
-		case engine.ExecuteTableFull:
-			fmt.Println("Error: Table full.")
-			continue
-		case engine.PrepareSuccess, engine.ExecuteSuccess:
-			fmt.Println("Executed.")
-		case engine.MetaUnrecognizedCommand:
-			fmt.Printf("Unrecognized command '%s'\n", string(l))
-		case engine.PrepareUnrecognizedStatement:
-			fmt.Printf("Unrecognized keyword at start of '%s'.\n", string(l))
-		case engine.PrepareSyntaxError:
-			fmt.Printf("Error on executing `%s`.\n", string(l))
-		case engine.PrepareStringTooLong:
-			fmt.Println("String is too long.")
-		case engine.PrepareNegativeId:
-			fmt.Println("ID must be positive.")
-		case engine.ExecutePageFetchError:
-			os.Exit(int(engine.ExecutePageFetchError))
-		case engine.ExecuteDuplicateKey:
-			fmt.Println("Error: Duplicate key.")
-		case engine.TODO:
-			fmt.Println("Need to implement searching an internal node")
-			os.Exit(int(engine.TODO))
+		l, err := input()
+		if err != nil {
+			if err != io.EOF {
+				fmt.Println(err)
+				failed = true
+			}
+			break
 		}
+
+		// scripts may contain blank lines and comments, but not the REPL
+		if !interactive {
+			l = []byte(strings.TrimSpace(string(l)))
+			if len(l) == 0 || strings.HasPrefix(string(l), "--") {
+				continue
+			}
+		}
+
+		err = process(l)
+		if errors.Is(err, engine.ErrExit) {
+			break
+		}
+		if !report(l, err) {
+			failed = true
+		}
+	}
+
+	closeInput()
+	if err := table.Close(); err != nil {
+		fmt.Println(err)
+		os.Exit(int(engine.StatusOf(err)))
+	}
+	if failed && !interactive {
+		os.Exit(int(engine.ExitFailure))
 	}
 }
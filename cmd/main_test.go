@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// TestMain runs main instead of the tests when the test binary is started by
+// run, so the CLI is tested as a process with its flags and exit status.
+func TestMain(m *testing.M) {
+	if os.Getenv("SQLTUT_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// run runs the CLI with args and returns its output and exit status.
+func run(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "SQLTUT_RUN_MAIN=1")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out), 0
+}
+
+// writeScript writes the lines of a script into a temporary file.
+func writeScript(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "script.sql")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestScriptFile(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	script := writeScript(t,
+		"-- users",
+		"insert 1 user1 person1@example.com",
+		"",
+		"insert 2 user2 person2@example.com",
+		"select",
+	)
+	out, code := run(t, "-db-path", dbPath, "-f", script)
+	if want := "Executed.\nExecuted.\n(1, user1, person1@example.com)\n(2, user2, person2@example.com)\nExecuted.\n"; out != want || code != 0 {
+		t.Errorf("-f %s = %q, %d, want %q, 0", script, out, code, want)
+	}
+
+	// the exit status of a script is non-zero if a statement fails
+	script = writeScript(t, "update 1", "select")
+	out, code = run(t, "-db-path", dbPath, "-f", script)
+	if code == 0 || !strings.Contains(out, "Unrecognized keyword") {
+		t.Errorf("-f with a failing statement = %q, %d, want a non-zero status", out, code)
+	}
+
+	if out, code := run(t, "-db-path", dbPath, "-f", filepath.Join(t.TempDir(), "missing.sql")); code == 0 {
+		t.Errorf("-f of a missing file = %q, %d, want a non-zero status", out, code)
+	}
+}
+
+func TestOutputMeta(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	if out, code := run(t, "-db-path", dbPath, "-c", "insert 1 user1 person1@example.com"); code != 0 {
+		t.Fatalf("insert = %q, %d", out, code)
+	}
+
+	// .mode and .headers are run in scripts of .read too
+	script := writeScript(t, ".mode csv", ".headers on", "select", ".headers off", "select")
+	out, code := run(t, "-db-path", dbPath, "-c", ".read "+script)
+	if want := "id,username,email\n1,user1,person1@example.com\n1,user1,person1@example.com\n"; out != want || code != 0 {
+		t.Errorf(".read %s = %q, %d, want %q, 0", script, out, code, want)
+	}
+
+	for _, command := range []string{".headers maybe", ".mode xml"} {
+		if out, code := run(t, "-db-path", dbPath, "-c", command); code == 0 {
+			t.Errorf("%s = %q, %d, want a non-zero status", command, out, code)
+		}
+	}
+}
+
+func TestUnknownEngine(t *testing.T) {
+	out, code := run(t, "-engine", "nope", "-db-path", filepath.Join(t.TempDir(), "test.db"), "-c", "select")
+	if want := "Engine not found, nope (see -engine list)\n"; out != want || code == 0 {
+		t.Errorf("-engine nope = %q, %d, want %q and a non-zero status", out, code, want)
+	}
+}
+
+// openDB opens the DB file at path by engine and executes commands on it.
+func openDB(t *testing.T, name, path string, commands ...string) engine.Storage {
+	t.Helper()
+
+	storage, err := engine.OpenStorage(name, path, engine.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range commands {
+		if _, err := engine.Exec(context.Background(), []byte(command), storage); err != nil {
+			storage.Close()
+			t.Fatalf("Exec(%q) error = %v", command, err)
+		}
+	}
+
+	return storage
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.db"), filepath.Join(dir, "dst.db")
+	closeDB := func(storage engine.Storage) {
+		t.Helper()
+		if err := storage.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	closeDB(openDB(t, "arraylike", src,
+		"insert 1 user1 person1@example.com",
+		"insert 2 user2 person2@example.com",
+		"alter table users add column age not null default 18",
+		"insert 3 user3 person3@example.com 30",
+	))
+	// the destination has a row of the same id and not the added column
+	closeDB(openDB(t, "btree", dst, "insert 2 other2 other2@example.com"))
+
+	out, code := run(t, "migrate", "-from", "arraylike", "-to", "btree", src, dst)
+	if want := "Duplicate key: (2, user2, person2@example.com, 18)\nMigrated 2 of 3 rows, 1 duplicates.\n"; out != want || code != 0 {
+		t.Errorf("migrate = %q, %d, want %q, 0", out, code, want)
+	}
+
+	storage := openDB(t, "btree", dst)
+	defer storage.Close()
+	result, err := engine.Exec(context.Background(), []byte("select"), storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,username,email,age"; strings.Join(result.Columns, ",") != want {
+		t.Errorf("Columns of the destination = %v, want %s", result.Columns, want)
+	}
+	var rows []string
+	for result.Next() {
+		rows = append(rows, result.Row().String())
+	}
+	if got, want := strings.Join(rows, "\n"), "(1, user1, person1@example.com, 18)\n(2, other2, other2@example.com, 18)\n(3, user3, person3@example.com, 30)"; got != want {
+		t.Errorf("Rows of the destination = %q, want %q", got, want)
+	}
+	if schema := engine.SchemaOf(storage); !schema[3].NotNull || schema[3].Default != engine.NewInteger(18) {
+		t.Errorf("Added column of the destination = %+v, want NOT NULL with default 18", schema[3])
+	}
+}
+
+func TestMigrateSchemaMismatch(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.db"), filepath.Join(dir, "dst.db")
+	if err := openDB(t, "arraylike", src, "alter table users add column age", "insert 1 user1 person1@example.com 30").Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := openDB(t, "btree", dst, "alter table users add column nick").Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, code := run(t, "migrate", "-from", "arraylike", "-to", "btree", src, dst)
+	if code == 0 || !strings.Contains(out, "Destination column nick doesn't match age") {
+		t.Errorf("migrate = %q, %d, want a non-zero status", out, code)
+	}
+}
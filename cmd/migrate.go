@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// migrate copies every row of a db file stored by one engine into a db file of
+// another engine, e.g. `sqltut migrate -from arraylike -to btree src dst`.
+func migrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	from := fs.String("from", "arraylike", "Engine of the source DB file")
+	to := fs.String("to", "btree", "Engine of the destination DB file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s migrate [-from engine] [-to engine] src dst\n", flag.CommandLine.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return int(engine.ExitFailure)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return int(engine.ExitFailure)
+	}
+	srcPath, dstPath := fs.Arg(0), fs.Arg(1)
+	ctx := context.Background()
+
+	src, err := engine.OpenStorage(*from, srcPath, engine.Options{ReadOnly: true})
+	if err != nil {
+		fmt.Printf("Unable to open source: %s\n", err)
+		return int(engine.ExitFailure)
+	}
+	defer src.Close()
+
+	// rows which were stored before columns were added get their defaults of
+	// the source, the destination may not have the same ones
+	rows, err := engine.SelectRows(ctx, src)
+	if err != nil {
+		fmt.Printf("Unable to read rows from source: %s\n", err)
+		return int(engine.StatusOf(err))
+	}
+
+	dst, err := engine.OpenStorage(*to, dstPath, engine.Options{})
+	if err != nil {
+		fmt.Printf("Unable to open destination: %s\n", err)
+		return int(engine.ExitFailure)
+	}
+
+	existing, err := dst.Select(ctx)
+	if err != nil {
+		fmt.Printf("Unable to read rows from destination: %s\n", err)
+		dst.Close()
+		return int(engine.StatusOf(err))
+	}
+
+	if err := migrateSchema(engine.SchemaOf(src), dst); err != nil {
+		fmt.Printf("Unable to copy the schema: %s\n", err)
+		dst.Close()
+		return int(engine.StatusOf(err))
+	}
+
+	var inserted, duplicates int
+	for _, row := range rows {
+		err := dst.Insert(row)
+		switch {
+		case err == nil:
+			inserted++
+		case errors.Is(err, engine.ErrDuplicateKey):
+			duplicates++
+			fmt.Printf("Duplicate key: %s\n", row)
+		default:
+			fmt.Printf("Unable to insert %s: %s\n", row, err)
+			dst.Close()
+			return int(engine.StatusOf(err))
+		}
+	}
+
+	migrated, err := dst.Select(ctx)
+	if err != nil {
+		fmt.Printf("Unable to verify destination: %s\n", err)
+		dst.Close()
+		return int(engine.StatusOf(err))
+	}
+	if err := dst.Close(); err != nil {
+		fmt.Println(err)
+		return int(engine.StatusOf(err))
+	}
+
+	fmt.Printf("Migrated %d of %d rows, %d duplicates.\n", inserted, len(rows), duplicates)
+	if len(migrated) != len(existing)+inserted {
+		fmt.Printf("Row count mismatch: destination has %d rows, expected %d.\n", len(migrated), len(existing)+inserted)
+		return int(engine.ExitFailure)
+	}
+
+	return 0
+}
+
+// migrateSchema adds the columns of schema which dst doesn't have yet, so
+// rows with columns added by ALTER TABLE fit in it.
+func migrateSchema(schema []engine.Column, dst engine.Storage) error {
+	current := engine.SchemaOf(dst)
+	if len(current) > len(schema) {
+		return fmt.Errorf("Destination has %d columns, source has %d", len(current), len(schema))
+	}
+	for i, column := range current {
+		if column.Name != schema[i].Name {
+			return fmt.Errorf("Destination column %s doesn't match %s", column.Name, schema[i].Name)
+		}
+	}
+	if len(schema) == len(current) {
+		return nil
+	}
+
+	s, ok := dst.(engine.Schemaer)
+	if !ok {
+		return fmt.Errorf("Destination has %d columns, source has %d", len(current), len(schema))
+	}
+	for _, column := range schema[len(current):] {
+		if err := s.AddColumn(column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/output"
+)
+
+var (
+	outputMode    string
+	outputHeaders bool
+	writer        engine.ResultWriter
+)
+
+// replWriter writes results by the writer of the current output mode and runs
+// `.mode` and `.headers`, also when they're in a script which is run by
+// `.read`.
+type replWriter struct{}
+
+func (replWriter) WriteRows(columns []string, rows []*engine.Row) error {
+	return writer.WriteRows(columns, rows)
+}
+
+func (replWriter) ExecuteMeta(command []byte) (bool, error) {
+	return processOutputMeta(command)
+}
+
+// setOutput changes how the result of statements are printed.
+func setOutput(mode output.Mode, headers bool) error {
+	w, err := output.New(mode, os.Stdout, headers)
+	if err != nil {
+		return err
+	}
+
+	outputMode, outputHeaders, writer = string(mode), headers, w
+
+	return nil
+}
+
+// processOutputMeta handles `.mode` and `.headers` which are owned by the REPL
+// rather than the storage. The first result is false for other commands.
+func processOutputMeta(l []byte) (bool, error) {
+	name, arg, _ := bytes.Cut(l, []byte(" "))
+	arg = bytes.TrimSpace(arg)
+
+	switch {
+	case engine.Equal(name, ".mode"):
+		if len(arg) == 0 {
+			fmt.Println(outputMode)
+			return true, nil
+		}
+		if err := setOutput(output.Mode(arg), outputHeaders); err != nil {
+			return true, engine.NewError(engine.MetaCommandFailure, err)
+		}
+	case engine.Equal(name, ".headers"):
+		var headers bool
+		switch {
+		case engine.Equal(arg, "on"):
+			headers = true
+		case engine.Equal(arg, "off"):
+			headers = false
+		default:
+			return true, engine.ErrSyntax
+		}
+		if err := setOutput(output.Mode(outputMode), headers); err != nil {
+			return true, engine.NewError(engine.MetaCommandFailure, err)
+		}
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/server"
+)
+
+// serve shares a db file with the clients of a TCP address, e.g.
+// `sqltut serve -listen :5433 -engine btree -db-path ./db`.
+func serve(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := fs.String("listen", ":5433", "TCP address to listen on")
+	protocol := fs.String("protocol", "sqltut", "Protocol of clients (sqltut, postgres and http)")
+	fs.StringVar(&dbPath, "db-path", dbPath, "Path of the DB file")
+	fs.StringVar(&dbEngine, "engine", dbEngine, "Engine to store and query")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s serve [-listen addr] [-protocol sqltut|postgres|http] [-engine engine] [-db-path path]\n", flag.CommandLine.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return int(engine.ExitFailure)
+	}
+
+	var serveFunc func(*server.Server, net.Listener) error
+	switch *protocol {
+	case "sqltut":
+		serveFunc = (*server.Server).Serve
+	case "postgres":
+		serveFunc = (*server.Server).ServePostgres
+	case "http":
+		serveFunc = (*server.Server).ServeAPI
+	default:
+		fmt.Printf("Protocol not found, %s\n", *protocol)
+		return int(engine.ExitFailure)
+	}
+
+	table, err := engine.OpenStorage(dbEngine, dbPath, engine.Options{ReadOnly: readOnly, BusyTimeout: busyTimeout, MMap: useMmap})
+	if errors.Is(err, engine.ErrEngineNotFound) {
+		fmt.Printf("%s (see -engine list)\n", err)
+		return int(engine.ExitFailure)
+	} else if errors.Is(err, engine.ErrLocked) {
+		fmt.Println("Error: Database is locked.")
+		return int(engine.ExecuteLocked)
+	} else if err != nil {
+		fmt.Println("Unable to open file")
+		return int(engine.ExitFailure)
+	}
+
+	l, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Println(err)
+		table.Close()
+		return int(engine.ExitFailure)
+	}
+	fmt.Printf("Listening on %s (%s)\n", l.Addr(), *protocol)
+
+	s := server.New(table)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		s.Close()
+	}()
+
+	status := 0
+	if err := serveFunc(s, l); !errors.Is(err, net.ErrClosed) {
+		fmt.Println(err)
+		status = int(engine.ExitFailure)
+	}
+	if err := table.Close(); err != nil {
+		fmt.Println(err)
+		return int(engine.StatusOf(err))
+	}
+
+	return status
+}
+
+// remote runs the statements on a server instead of a local db file.
+type remote struct {
+	client *server.Client
+}
+
+func (r *remote) process(l []byte) error {
+	// the server doesn't run meta commands, so leave the session here
+	if engine.Equal(l, ".exit") {
+		return engine.ErrExit
+	}
+	if ok, err := processOutputMeta(l); ok {
+		return err
+	}
+
+	response, err := r.client.Exec(l)
+	if err != nil {
+		return err
+	}
+	if response.Columns != nil {
+		return writer.WriteRows(response.Columns, response.Rows)
+	}
+
+	return nil
+}
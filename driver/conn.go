@@ -0,0 +1,172 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// ErrNoRollback is the error of rolling back a transaction. Storages apply
+// statements as they're executed, so a rollback only ends the transaction.
+var ErrNoRollback = errors.New("sqltut: rollback is not supported, statements of the transaction are already applied")
+
+type conn struct {
+	connector *connector
+	// owner connections are opened by Driver.Open and close the DB file.
+	owner bool
+	// tx is the running transaction, statements of the connection don't
+	// wait for its lock.
+	tx *tx
+}
+
+func (c *conn) Prepare(query string) (sqldriver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (sqldriver.Stmt, error) {
+	statement, err := engine.PrepareStatement([]byte(query))
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{conn: c, statement: statement}, nil
+}
+
+func (c *conn) Close() error {
+	if c.owner {
+		return c.connector.Close()
+	}
+
+	return nil
+}
+
+func (c *conn) Begin() (sqldriver.Tx, error) {
+	return c.BeginTx(context.Background(), sqldriver.TxOptions{})
+}
+
+// BeginTx waits for the statements of other connections and holds the DB file
+// until the transaction ends, so transactions are serializable.
+func (c *conn) BeginTx(ctx context.Context, opts sqldriver.TxOptions) (sqldriver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("sqltut: transaction is already running")
+	}
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault, sql.LevelSerializable:
+	default:
+		return nil, fmt.Errorf("sqltut: isolation level %v is not supported", sql.IsolationLevel(opts.Isolation))
+	}
+
+	c.connector.txMu.Lock()
+	if err := ctx.Err(); err != nil {
+		c.connector.txMu.Unlock()
+		return nil, err
+	}
+	c.tx = &tx{conn: c}
+
+	return c.tx, nil
+}
+
+// exec runs the statement on the storage, after the running transaction of
+// another connection.
+func (c *conn) exec(ctx context.Context, statement *engine.Statement, args []sqldriver.NamedValue) (*engine.Result, error) {
+	if c.tx == nil {
+		c.connector.txMu.RLock()
+		defer c.connector.txMu.RUnlock()
+	}
+
+	return c.connector.exec(ctx, statement, args)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	s, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.(*stmt).ExecContext(ctx, args)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	s, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.(*stmt).QueryContext(ctx, args)
+}
+
+// tx holds the DB file for its connection. Statements are applied as they're
+// executed, Commit flushes them into the DB file and Rollback fails with
+// ErrNoRollback.
+type tx struct {
+	conn *conn
+}
+
+func (t *tx) Commit() error {
+	defer t.end()
+
+	return t.conn.connector.flush()
+}
+
+func (t *tx) Rollback() error {
+	t.end()
+
+	return ErrNoRollback
+}
+
+func (t *tx) end() {
+	t.conn.tx = nil
+	t.conn.connector.txMu.Unlock()
+}
+
+type stmt struct {
+	conn      *conn
+	statement *engine.Statement
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return s.statement.NumParams()
+}
+
+func (s *stmt) Exec(args []sqldriver.Value) (sqldriver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []sqldriver.Value) (sqldriver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	result, err := s.conn.exec(ctx, s.statement, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqldriver.RowsAffected(result.RowsAffected), nil
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	result, err := s.conn.exec(ctx, s.statement, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rows{result: result}, nil
+}
+
+func namedValues(args []sqldriver.Value) []sqldriver.NamedValue {
+	named := make([]sqldriver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = sqldriver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+
+	return named
+}
@@ -0,0 +1,179 @@
+// Package driver registers sqltut as a database/sql driver named "sqltut".
+//
+// The data source name is the path of the DB file, optionally followed by
+// parameters, e.g. "./db?engine=btree&busy_timeout=5s". Parameters are:
+//
+//	engine        registered storage engine, arraylike (default) or btree
+//	readonly      open the DB file as read only, sharing it with other readers
+//	busy_timeout  how long to wait for a DB file locked by another process
+//	mmap          read the DB file through a memory mapping (btree only)
+//
+// Statements accept `?`, `$1` and `:name` placeholders:
+//
+//	db, err := sql.Open("sqltut", "./db?engine=btree")
+//	_, err = db.Exec("insert ? ? ?", 1, "user1", "person1@example.com")
+//	_, err = db.Exec("insert :id :name :email", sql.Named("id", 2), ...)
+//
+// A transaction holds the DB file until it ends, Commit flushes it into the
+// file. Statements can't be undone, so Rollback fails with ErrNoRollback.
+package driver
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+	_ "github.com/meysampg/sqltut/engine/storage/arraylike"
+	_ "github.com/meysampg/sqltut/engine/storage/btree"
+)
+
+func init() {
+	sql.Register("sqltut", &Driver{})
+}
+
+// Driver opens sqltut DB files for database/sql.
+type Driver struct{}
+
+// Open opens a connection which owns its DB file. database/sql uses
+// OpenConnector instead, so all connections of a sql.DB share one file.
+func (d *Driver) Open(name string) (sqldriver.Conn, error) {
+	c, err := d.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{connector: c.(*connector), owner: true}, nil
+}
+
+// OpenConnector opens the DB file of the data source name.
+func (d *Driver) OpenConnector(name string) (sqldriver.Connector, error) {
+	path, typ, opts, err := parseDSN(name)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := engine.OpenStorage(typ, path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &connector{driver: d, storage: storage}, nil
+}
+
+func parseDSN(name string) (string, string, engine.Options, error) {
+	var opts engine.Options
+	path, query, _ := strings.Cut(name, "?")
+	if path == "" {
+		return "", "", opts, fmt.Errorf("sqltut: missing DB path in %q", name)
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", "", opts, fmt.Errorf("sqltut: invalid parameters in %q: %w", name, err)
+	}
+	typ := params.Get("engine")
+	if typ == "" {
+		typ = engine.DefaultEngine
+	}
+	if v := params.Get("readonly"); v != "" {
+		if opts.ReadOnly, err = strconv.ParseBool(v); err != nil {
+			return "", "", opts, fmt.Errorf("sqltut: invalid readonly in %q: %w", name, err)
+		}
+	}
+	if v := params.Get("busy_timeout"); v != "" {
+		if opts.BusyTimeout, err = time.ParseDuration(v); err != nil {
+			return "", "", opts, fmt.Errorf("sqltut: invalid busy_timeout in %q: %w", name, err)
+		}
+	}
+	if v := params.Get("mmap"); v != "" {
+		if opts.MMap, err = strconv.ParseBool(v); err != nil {
+			return "", "", opts, fmt.Errorf("sqltut: invalid mmap in %q: %w", name, err)
+		}
+	}
+
+	return path, typ, opts, nil
+}
+
+// connector shares one storage between the connections of a sql.DB.
+type connector struct {
+	driver  *Driver
+	storage engine.Storage
+	// mu guards closed, statements hold a read lock while they're running.
+	mu     sync.RWMutex
+	closed bool
+	// txMu is held by a transaction, statements of other connections hold a
+	// read lock while they're running.
+	txMu sync.RWMutex
+}
+
+func (c *connector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return nil, sqldriver.ErrBadConn
+	}
+
+	return &conn{connector: c}, nil
+}
+
+func (c *connector) Driver() sqldriver.Driver {
+	return c.driver
+}
+
+// Close flushes and closes the DB file. It's called by sql.DB.Close.
+func (c *connector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	return c.storage.Close()
+}
+
+// flush writes the changes of the storage into the DB file, if it's a Flusher.
+func (c *connector) flush() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return sqldriver.ErrBadConn
+	}
+
+	if f, ok := c.storage.(engine.Flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// exec binds args to the statement and runs it while holding the storage.
+func (c *connector) exec(ctx context.Context, statement *engine.Statement, args []sqldriver.NamedValue) (*engine.Result, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return nil, sqldriver.ErrBadConn
+	}
+
+	statement.ClearBindings()
+	for _, arg := range args {
+		var err error
+		if arg.Name != "" {
+			err = statement.BindName(arg.Name, arg.Value)
+		} else {
+			err = statement.Bind(arg.Ordinal, arg.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return statement.Exec(ctx, c.storage)
+}
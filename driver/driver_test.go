@@ -0,0 +1,169 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+func TestDriver(t *testing.T) {
+	for _, typ := range []string{"arraylike", "btree"} {
+		t.Run(typ, func(t *testing.T) {
+			dsn := filepath.Join(t.TempDir(), "test.db") + "?engine=" + typ
+			db, err := sql.Open("sqltut", dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, name := range []string{"user1", "user2"} {
+				result, err := db.Exec("insert ? ? ?", i+1, name, name+"@example.com")
+				if err != nil {
+					t.Fatalf("Exec() error = %v", err)
+				}
+				if n, _ := result.RowsAffected(); n != 1 {
+					t.Errorf("RowsAffected() = %d, want 1", n)
+				}
+			}
+			if _, err := db.Exec("insert :id :name $3", sql.Named("id", 3), sql.Named("name", "user 3"), "person3@example.com"); err != nil {
+				t.Fatalf("Exec() with named arguments error = %v", err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			// data is kept after reopening the db
+			db, err = sql.Open("sqltut", dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			var id int64
+			var username, email string
+			if err := db.QueryRow("select").Scan(&id, &username, &email); err != nil {
+				t.Fatalf("QueryRow() error = %v", err)
+			}
+			if id < 1 || id > 3 {
+				t.Errorf("QueryRow() id = %d, want 1, 2 or 3", id)
+			}
+
+			rows, err := db.Query("select")
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			defer rows.Close()
+			columns, _ := rows.Columns()
+			if len(columns) != 3 || columns[0] != "id" {
+				t.Errorf("Columns() = %v, want %v", columns, engine.Columns)
+			}
+			n := 0
+			for rows.Next() {
+				n++
+			}
+			if n != 3 {
+				t.Errorf("Query() returned %d rows, want 3", n)
+			}
+		})
+	}
+}
+
+func TestDriverErrors(t *testing.T) {
+	db, err := sql.Open("sqltut", filepath.Join(t.TempDir(), "test.db")+"?engine=btree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("insert 1 user1 person1@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert 1 user1 person1@example.com"); !errors.Is(err, engine.ErrDuplicateKey) {
+		t.Errorf("Exec() duplicate error = %v, want %v", err, engine.ErrDuplicateKey)
+	}
+
+	if _, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelReadCommitted}); err == nil {
+		t.Errorf("BeginTx() with read committed isolation error = nil, want an error")
+	}
+
+	if _, err := sql.Open("sqltut", "test.db?engine=unknown"); !errors.Is(err, engine.ErrEngineNotFound) {
+		t.Errorf("Open() with unknown engine error = %v, want %v", err, engine.ErrEngineNotFound)
+	}
+}
+
+func TestDriverTx(t *testing.T) {
+	for _, typ := range []string{"arraylike", "btree"} {
+		t.Run(typ, func(t *testing.T) {
+			db, err := sql.Open("sqltut", filepath.Join(t.TempDir(), "test.db")+"?engine="+typ)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			insert := func(e interface {
+				Exec(string, ...any) (sql.Result, error)
+			}, id int) error {
+				_, err := e.Exec("insert ? ? ?", id, fmt.Sprintf("user%d", id), fmt.Sprintf("person%d@example.com", id))
+				return err
+			}
+
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("Begin() error = %v", err)
+			}
+			for id := 1; id <= 3; id++ {
+				if err := insert(tx, id); err != nil {
+					t.Fatalf("Exec() in the transaction error = %v", err)
+				}
+			}
+			// statements out of the transaction wait for it
+			done := make(chan error)
+			go func() { done <- insert(db, 4) }()
+			select {
+			case err := <-done:
+				t.Errorf("Exec() out of the transaction = %v, want it to wait for Commit", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatalf("Commit() error = %v", err)
+			}
+			if err := <-done; err != nil {
+				t.Fatalf("Exec() out of the transaction error = %v", err)
+			}
+
+			tx, err = db.Begin()
+			if err != nil {
+				t.Fatalf("Begin() error = %v", err)
+			}
+			if err := insert(tx, 5); err != nil {
+				t.Fatalf("Exec() in the transaction error = %v", err)
+			}
+			if err := tx.Rollback(); !errors.Is(err, ErrNoRollback) {
+				t.Errorf("Rollback() error = %v, want %v", err, ErrNoRollback)
+			}
+
+			// pages flushed by Commit are read back from the DB file
+			if err := insert(db, 6); err != nil {
+				t.Fatalf("Exec() error = %v", err)
+			}
+			var n int
+			rows, err := db.Query("select")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for rows.Next() {
+				n++
+			}
+			if err := rows.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if n != 6 {
+				t.Errorf("Query() returned %d rows, want 6", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package driver
+
+import (
+	sqldriver "database/sql/driver"
+	"io"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+type rows struct {
+	result *engine.Result
+}
+
+func (r *rows) Columns() []string {
+	return r.result.Columns
+}
+
+func (r *rows) Close() error {
+	for r.result.Next() {
+	}
+
+	return nil
+}
+
+func (r *rows) Next(dest []sqldriver.Value) error {
+	if !r.result.Next() {
+		return io.EOF
+	}
+
+	for i, v := range r.result.Row().Values() {
+		dest[i] = v.Interface()
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prepareAlter parses `alter table <table> add [column] <name> [not null]
+// [default <value>]`.
+func prepareAlter(command []byte, statement *Statement) error {
+	tokens, err := tokenize(command)
+	if err != nil {
+		return err
+	}
+
+	keywords := []string{string(StatementAlter), "table", "", "add"}
+	for i, keyword := range keywords {
+		if i >= len(tokens) {
+			return syntaxError(PrepareSyntaxError, command, len(command))
+		}
+		if keyword != "" && !strings.EqualFold(tokens[i].text, keyword) {
+			return syntaxError(PrepareSyntaxError, command, tokens[i].pos)
+		}
+	}
+	if !strings.EqualFold(tokens[2].text, TableName) {
+		return &Error{Status: PrepareSyntaxError, Statement: string(command), Position: tokens[2].pos, Err: fmt.Errorf("No table named %s", tokens[2].text)}
+	}
+	tokens = tokens[len(keywords):]
+	if len(tokens) > 0 && strings.EqualFold(tokens[0].text, "column") {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return syntaxError(PrepareSyntaxError, command, len(command))
+	}
+
+	name := tokens[0]
+	if !isIdentifier(name.text) {
+		return syntaxError(PrepareSyntaxError, command, name.pos)
+	}
+	column := &Column{Name: name.text}
+	for tokens = tokens[1:]; len(tokens) > 0; {
+		switch {
+		case len(tokens) > 1 && strings.EqualFold(tokens[0].text, "not") && strings.EqualFold(tokens[1].text, "null"):
+			column.NotNull = true
+		case len(tokens) > 1 && strings.EqualFold(tokens[0].text, "default"):
+			// placeholders aren't bound by ALTER TABLE
+			f, ok := (&params{}).parseField(tokens[1])
+			if !ok || f.param != 0 {
+				return syntaxError(PrepareSyntaxError, command, tokens[1].pos)
+			}
+			if f.value.Len() > 255 {
+				return syntaxError(PrepareStringTooLong, command, tokens[1].pos)
+			}
+			column.Default = f.value
+		default:
+			return syntaxError(PrepareSyntaxError, command, tokens[0].pos)
+		}
+		tokens = tokens[2:]
+	}
+	if column.NotNull && column.Default.IsNull() {
+		return &Error{Status: PrepareSyntaxError, Statement: string(command), Position: name.pos, Err: fmt.Errorf("Cannot add a NOT NULL column with default value NULL")}
+	}
+	statement.column = column
+
+	return nil
+}
+
+// addColumn executes an ALTER TABLE against storage.
+func (s *Statement) addColumn(storage Storage) error {
+	schemaer, ok := storage.(Schemaer)
+	if !ok {
+		return &Error{Status: PrepareUnrecognizedStatement, Statement: s.String(), Position: 0, Err: fmt.Errorf("Storage doesn't support ALTER TABLE")}
+	}
+	if columnIndex(schemaer.Schema(), s.column.Name) >= 0 {
+		return &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: -1, Err: fmt.Errorf("Duplicate column name %s", s.column.Name)}
+	}
+
+	if err := schemaer.AddColumn(*s.column); err != nil {
+		return withStatement(err, s.text)
+	}
+
+	return nil
+}
+
+// definition returns the column as it's given to ALTER TABLE.
+func (c Column) definition() string {
+	definition := c.Name
+	if c.NotNull {
+		definition += " not null"
+	}
+	if !c.Default.IsNull() {
+		definition += " default " + c.Default.Literal()
+	}
+
+	return definition
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+
+	return s != ""
+}
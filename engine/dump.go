@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const dumpHeader = "-- sqltut dump"
+
+// Dump writes a script of statements which recreates the added columns and
+// every row of the storage when it's replayed by Read.
+func Dump(w io.Writer, storage Storage) error {
+	schema := SchemaOf(storage)
+	rows, err := selectRows(context.Background(), storage, schema)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, dumpHeader); err != nil {
+		return NewError(MetaCommandFailure, err)
+	}
+	for _, column := range schema[len(Schema):] {
+		if _, err := fmt.Fprintf(w, "%s table %s add column %s\n", StatementAlter, TableName, column.definition()); err != nil {
+			return NewError(MetaCommandFailure, err)
+		}
+	}
+	for _, row := range rows {
+		literals := []string{string(StatementInsert)}
+		for _, v := range row.Values() {
+			literals = append(literals, v.Literal())
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(literals, " ")); err != nil {
+			return NewError(MetaCommandFailure, err)
+		}
+	}
+
+	return nil
+}
+
+// Read executes a script line by line and stops on the first statement which
+// fails. Empty lines and lines starting with `--` are skipped.
+func Read(r io.Reader, storage Storage, writer ResultWriter) error {
+	return read(r, storage, writer, 0)
+}
+
+func read(r io.Reader, storage Storage, writer ResultWriter, depth int) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || bytes.HasPrefix(line, []byte("--")) {
+			continue
+		}
+
+		err := process(line, storage, writer, depth)
+		if errors.Is(err, ErrExit) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("Error on line %d: %w", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return NewError(MetaCommandFailure, err)
+	}
+
+	return nil
+}
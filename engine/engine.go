@@ -2,39 +2,55 @@ package engine
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 )
 
-func Process(command []byte, storage Storage) ExecutionStatus {
-	if bytes.HasPrefix(command, []byte(".")) {
-		return processMeta(command, storage)
-	} else {
-		return execute(command, storage)
-	}
+// Process executes a statement or a meta command and writes the rows of the
+// result into writer. `.exit` returns ErrExit and leaves closing the storage
+// and stopping to the caller.
+func Process(command []byte, storage Storage, writer ResultWriter) error {
+	return process(command, storage, writer, 0)
 }
 
-func Equal(a []byte, b string) bool {
-	return bytes.Equal(a, []byte(b))
-}
+// process is Process of a command of a script which is nested in depth
+// `.read` commands.
+func process(command []byte, storage Storage, writer ResultWriter, depth int) error {
+	if bytes.HasPrefix(command, []byte(".")) {
+		if err := processMeta(command, storage, writer, depth); err != nil {
+			return withStatement(err, command)
+		}
+		return nil
+	}
 
-func execute(command []byte, storage Storage) ExecutionStatus {
-	statement, status := PrepareStatement(command)
-	if status != PrepareSuccess {
-		return status
+	result, err := Exec(context.Background(), command, storage)
+	if err != nil {
+		return err
 	}
 
-	switch statement.Type {
-	case StatementInsert:
-		return storage.Insert(statement.RowToInsert)
-	case StatementSelect:
-		result, status := storage.Select()
-		if status == ExecuteSuccess {
-			for _, row := range result {
-				fmt.Println(row)
-			}
+	if result.Columns != nil {
+		var rows []*Row
+		for result.Next() {
+			rows = append(rows, result.Row())
+		}
+		if err := writer.WriteRows(result.Columns, rows); err != nil {
+			return withStatement(err, command)
 		}
-		return status
 	}
 
-	return PrepareSuccess
+	return nil
+}
+
+// Exec executes a statement against storage and returns its result. Meta
+// commands are not statements and are handled by Process.
+func Exec(ctx context.Context, command []byte, storage Storage) (*Result, error) {
+	statement, err := PrepareStatement(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return statement.Exec(ctx, storage)
+}
+
+func Equal(a []byte, b string) bool {
+	return bytes.Equal(a, []byte(b))
 }
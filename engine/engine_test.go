@@ -0,0 +1,752 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+	_ "github.com/meysampg/sqltut/engine/storage/arraylike"
+	"github.com/meysampg/sqltut/engine/storage/btree"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
+)
+
+func TestExec(t *testing.T) {
+	table, err := btree.DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	ctx := context.Background()
+	for _, command := range []string{"insert 2 user2 person2@example.com", "insert 1 user1 person1@example.com"} {
+		result, err := engine.Exec(ctx, []byte(command), table)
+		if err != nil {
+			t.Fatalf("Exec(%q) error = %v", command, err)
+		}
+		if result.RowsAffected != 1 {
+			t.Errorf("Exec(%q) RowsAffected = %d, want 1", command, result.RowsAffected)
+		}
+	}
+
+	_, err = engine.Exec(ctx, []byte("insert 1 user1 person1@example.com"), table)
+	if !errors.Is(err, engine.ErrDuplicateKey) {
+		t.Errorf("Exec() duplicate error = %v, want %v", err, engine.ErrDuplicateKey)
+	}
+
+	result, err := engine.Exec(ctx, []byte("select"), table)
+	if err != nil {
+		t.Fatalf("Exec(select) error = %v", err)
+	}
+	if len(result.Columns) != len(engine.Columns) {
+		t.Errorf("Exec(select) Columns = %v, want %v", result.Columns, engine.Columns)
+	}
+	var ids []uint32
+	for result.Next() {
+		ids = append(ids, result.Row().Id)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Exec(select) ids = %v, want [1 2]", ids)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := engine.Exec(cancelled, []byte("select"), table); !errors.Is(err, context.Canceled) {
+		t.Errorf("Exec() with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestExecSyntaxError(t *testing.T) {
+	table, err := btree.DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	tests := []struct {
+		command  string
+		want     error
+		position int
+	}{
+		{command: "update 1", want: engine.ErrUnrecognizedStatement, position: 0},
+		{command: "insert 1 user1", want: engine.ErrSyntax, position: 14},
+		{command: "insert -1 user1 person1@example.com", want: engine.ErrNegativeId, position: 7},
+		{command: "insert user1 user1 person1@example.com", want: engine.ErrSyntax, position: 7},
+		{command: "insert 1 'user1 person1@example.com", want: engine.ErrSyntax, position: 9},
+		{command: "insert 1 x'0g' person1@example.com", want: engine.ErrSyntax, position: 9},
+		{command: "select where name = user1", want: engine.ErrSyntax, position: 13},
+		{command: "select where id ~ 1", want: engine.ErrSyntax, position: 16},
+		{command: "select where id is nothing", want: engine.ErrSyntax, position: 19},
+		{command: "insert NULL user1 person1@example.com", want: engine.ErrNotNull, position: 7},
+		{command: "insert 1 user1 " + strings.Repeat("a", 256), want: engine.ErrStringTooLong, position: 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			_, err := engine.Exec(context.Background(), []byte(tt.command), table)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Exec() error = %v, want %v", err, tt.want)
+			}
+			var e *engine.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Exec() error = %T, want *engine.Error", err)
+			}
+			if e.Statement != tt.command || e.Position != tt.position {
+				t.Errorf("Exec() error at %q:%d, want %q:%d", e.Statement, e.Position, tt.command, tt.position)
+			}
+		})
+	}
+}
+
+func TestStatementBind(t *testing.T) {
+	table, err := btree.DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	statement, err := engine.PrepareStatement([]byte("insert $1 :name ?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := statement.NumParams(); n != 3 {
+		t.Fatalf("NumParams() = %d, want 3", n)
+	}
+	for i, want := range []string{"", "name", ""} {
+		if name := statement.ParamName(i + 1); name != want {
+			t.Errorf("ParamName(%d) = %q, want %q", i+1, name, want)
+		}
+	}
+
+	ctx := context.Background()
+	if _, err := statement.Exec(ctx, table); !errors.Is(err, engine.ErrSyntax) {
+		t.Errorf("Exec() without bindings error = %v, want %v", err, engine.ErrSyntax)
+	}
+
+	for i := 1; i <= 3; i++ {
+		statement.Bind(1, i)
+		statement.BindName("name", fmt.Sprintf("user %d", i))
+		statement.Bind(3, []byte(fmt.Sprintf("person%d@example.com", i)))
+		if _, err := statement.Exec(ctx, table); err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+	}
+
+	statement.Bind(1, -1)
+	if _, err := statement.Exec(ctx, table); !errors.Is(err, engine.ErrNegativeId) {
+		t.Errorf("Exec() with negative id error = %v, want %v", err, engine.ErrNegativeId)
+	}
+	statement.Bind(1, nil)
+	if _, err := statement.Exec(ctx, table); !errors.Is(err, engine.ErrNotNull) {
+		t.Errorf("Exec() with NULL id error = %v, want %v", err, engine.ErrNotNull)
+	}
+	statement.Bind(1, "4.5")
+	if _, err := statement.Exec(ctx, table); !errors.Is(err, engine.ErrSyntax) {
+		t.Errorf("Exec() with REAL text id error = %v, want %v", err, engine.ErrSyntax)
+	}
+	// numeric TEXTs have INTEGER affinity like in `where id = '4'`
+	statement.Bind(1, "4")
+	statement.BindName("name", "user 4")
+	if _, err := statement.Exec(ctx, table); err != nil {
+		t.Errorf("Exec() with text id error = %v", err)
+	}
+	if err := statement.BindName("unknown", 1); !errors.Is(err, engine.ErrSyntax) {
+		t.Errorf("BindName() unknown error = %v, want %v", err, engine.ErrSyntax)
+	}
+
+	result, err := engine.Exec(ctx, []byte("select"), table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var usernames []string
+	for result.Next() {
+		usernames = append(usernames, result.Row().Fields[0].Text())
+	}
+	if strings.Join(usernames, ",") != "user 1,user 2,user 3,user 4" {
+		t.Errorf("Exec(select) usernames = %v, want [user 1 user 2 user 3 user 4]", usernames)
+	}
+}
+
+func TestStatementConcurrentExec(t *testing.T) {
+	table, err := btree.DbOpen(engine.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	statement, err := engine.PrepareStatement([]byte("insert ? user1 person1@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement.Bind(1, 1)
+
+	// a shared statement is only read by its executions
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := statement.Exec(context.Background(), table)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	inserted := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			inserted++
+		case !errors.Is(err, engine.ErrDuplicateKey):
+			t.Errorf("Exec() error = %v, want nil or %v", err, engine.ErrDuplicateKey)
+		}
+	}
+	if inserted != 1 {
+		t.Errorf("Exec() inserted %d times, want 1", inserted)
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	table, err := btree.DbOpen(engine.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	ctx := context.Background()
+	for _, command := range []string{
+		"insert 1 -5 'it''s a name'",
+		"insert 2 2.5 x'00ff'",
+		"insert 3 NULL 10",
+		"insert 4.0 user4 person4@example.com",
+	} {
+		if _, err := engine.Exec(ctx, []byte(command), table); err != nil {
+			t.Fatalf("Exec(%q) error = %v", command, err)
+		}
+	}
+
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{command: "select", want: []string{"(1, -5, it's a name)", "(2, 2.5, x'00ff')", "(3, NULL, 10)", "(4, user4, person4@example.com)"}},
+		{command: "select where username < 0", want: []string{"(1, -5, it's a name)"}},
+		{command: "select where username >= -5", want: []string{"(1, -5, it's a name)", "(2, 2.5, x'00ff')", "(4, user4, person4@example.com)"}},
+		{command: "select where email = x'00FF'", want: []string{"(2, 2.5, x'00ff')"}},
+		{command: "select where email > 9", want: []string{"(1, -5, it's a name)", "(2, 2.5, x'00ff')", "(3, NULL, 10)", "(4, user4, person4@example.com)"}},
+		{command: "select where username = NULL", want: nil},
+		{command: "select where username is null", want: []string{"(3, NULL, 10)"}},
+		{command: "select where username IS NOT NULL", want: []string{"(1, -5, it's a name)", "(2, 2.5, x'00ff')", "(4, user4, person4@example.com)"}},
+		{command: "select where id = '3'", want: []string{"(3, NULL, 10)"}},
+		{command: "select where username != 'user4'", want: []string{"(1, -5, it's a name)", "(2, 2.5, x'00ff')"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			result, err := engine.Exec(ctx, []byte(tt.command), table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for result.Next() {
+				got = append(got, result.Row().String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Exec() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	statement, err := engine.PrepareStatement([]byte("select where username < ?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement.Bind(1, 0.5)
+	result, err := statement.Exec(ctx, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Next() || result.Row().Id != 1 || result.Next() {
+		t.Errorf("Exec(username < 0.5) didn't return only the row 1")
+	}
+}
+
+func TestProcessExit(t *testing.T) {
+	table, err := btree.DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := filepath.Join(t.TempDir(), "script.sql")
+	if err := os.WriteFile(script, []byte("insert 1 user1 person1@example.com\n.exit\ninsert 2 user2 person2@example.com\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, command := range []string{".exit", ".read " + script} {
+		if err := engine.Process([]byte(command), table, nil); !errors.Is(err, engine.ErrExit) {
+			t.Errorf("Process(%q) error = %v, want %v", command, err, engine.ErrExit)
+		}
+	}
+
+	// the storage is left open to the caller
+	rows, err := table.Select(context.Background())
+	if err != nil {
+		t.Fatalf("Select() after .exit error = %v", err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("Select() = %d rows, want 1", len(rows))
+	}
+	if err := table.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	info, ok := engine.LookupEngine("btree")
+	if !ok {
+		t.Fatalf("LookupEngine(btree) not found in %v", engine.Engines())
+	}
+	if !info.Capabilities.Ranges {
+		t.Errorf("btree Capabilities.Ranges = false, want true")
+	}
+
+	storage, err := engine.OpenStorage("btree", filepath.Join(t.TempDir(), "test.db"), engine.Options{})
+	if err != nil {
+		t.Fatalf("OpenStorage(btree) error = %v", err)
+	}
+	storage.Close()
+
+	if _, err := engine.OpenStorage("nope", filepath.Join(t.TempDir(), "test.db"), engine.Options{}); !errors.Is(err, engine.ErrEngineNotFound) {
+		t.Errorf("OpenStorage(nope) error = %v, want %v", err, engine.ErrEngineNotFound)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() of a duplicate name didn't panic")
+		}
+	}()
+	engine.Register(info)
+}
+
+func TestMemoryStorage(t *testing.T) {
+	for _, name := range []string{"arraylike", "btree"} {
+		t.Run(name, func(t *testing.T) {
+			storage, err := engine.OpenStorage(name, engine.MemoryPath, engine.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			for i := 1; i <= 5; i++ {
+				command := fmt.Sprintf("insert %d user%d person%d@example.com", i, i, i)
+				if _, err := engine.Exec(ctx, []byte(command), storage); err != nil {
+					t.Fatalf("Exec(%q) error = %v", command, err)
+				}
+			}
+			rows, err := storage.Select(ctx)
+			if err != nil || len(rows) != 5 {
+				t.Errorf("Select() = %d rows, %v, want 5 rows", len(rows), err)
+			}
+			if n, err := engine.CountRows(ctx, storage); err != nil || n != 5 {
+				t.Errorf("CountRows() = %d, %v, want 5", n, err)
+			}
+			if err := storage.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+
+			if _, err := os.Stat(engine.MemoryPath); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Stat(%s) error = %v, want %v", engine.MemoryPath, err, os.ErrNotExist)
+			}
+
+			// every open is a new DB
+			storage, err = engine.OpenStorage(name, engine.MemoryPath, engine.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer storage.Close()
+			if rows, _ := storage.Select(ctx); len(rows) != 0 {
+				t.Errorf("Select() after reopening = %d rows, want 0", len(rows))
+			}
+		})
+	}
+}
+
+func TestAlterTable(t *testing.T) {
+	for _, name := range []string{"arraylike", "btree"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.db")
+			storage, err := engine.OpenStorage(name, path, engine.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			for _, command := range []string{
+				"insert 1 user1 person1@example.com",
+				"alter table users add column age",
+				"alter TABLE users ADD nick NOT NULL DEFAULT 'no one'",
+				"insert 2 user2 person2@example.com 30",
+				"insert 3 user3 person3@example.com NULL nick3",
+			} {
+				if _, err := engine.Exec(ctx, []byte(command), storage); err != nil {
+					t.Fatalf("Exec(%q) error = %v", command, err)
+				}
+			}
+			if err := storage.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// the columns are kept in the DB file
+			if files, _ := filepath.Glob(path + "*"); len(files) != 1 {
+				t.Errorf("Files of the DB = %v, want only %s", files, path)
+			}
+			storage, err = engine.OpenStorage(name, path, engine.Options{ReadOnly: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer storage.Close()
+
+			result, err := engine.Exec(ctx, []byte("select where nick = 'no one'"), storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "id,username,email,age,nick"; strings.Join(result.Columns, ",") != want {
+				t.Errorf("Exec(select) Columns = %v, want %v", result.Columns, want)
+			}
+			var got []string
+			for result.Next() {
+				got = append(got, result.Row().String())
+			}
+			if want := "(1, user1, person1@example.com, NULL, no one)\n(2, user2, person2@example.com, 30, no one)"; strings.Join(got, "\n") != want {
+				t.Errorf("Exec(select) = %q, want %q", got, want)
+			}
+
+			var dump strings.Builder
+			if err := engine.Dump(&dump, storage); err != nil {
+				t.Fatal(err)
+			}
+			if want := "alter table users add column age\nalter table users add column nick not null default 'no one'\n"; !strings.Contains(dump.String(), want) {
+				t.Errorf("Dump() = %q, want it to contain %q", dump.String(), want)
+			}
+		})
+	}
+}
+
+func TestMaxRowSize(t *testing.T) {
+	for _, name := range []string{"arraylike", "btree"} {
+		t.Run(name, func(t *testing.T) {
+			storage, err := engine.OpenStorage(name, filepath.Join(t.TempDir(), "test.db"), engine.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer storage.Close()
+
+			// the largest row of the columns of engine.Schema fits its slot
+			ctx := context.Background()
+			command := fmt.Sprintf("insert 4294967295 %s x'%s'", strings.Repeat("a", 255), strings.Repeat("bb", 255))
+			if _, err := engine.Exec(ctx, []byte(command), storage); err != nil {
+				t.Fatalf("Exec() of the largest row error = %v", err)
+			}
+			rows, err := storage.Select(ctx)
+			if err != nil || len(rows) != 1 || rows[0].Id != 1<<32-1 || rows[0].Fields[1].Len() != 255 {
+				t.Errorf("Select() = %v, %v, want the largest row", rows, err)
+			}
+
+			// added columns share the slot
+			if _, err := engine.Exec(ctx, []byte("alter table users add column bio"), storage); err != nil {
+				t.Fatal(err)
+			}
+			command = fmt.Sprintf("insert 2 %s %s %s", strings.Repeat("a", 255), strings.Repeat("b", 255), strings.Repeat("c", 255))
+			if _, err := engine.Exec(ctx, []byte(command), storage); !errors.Is(err, engine.ErrRowTooLarge) {
+				t.Errorf("Exec() of a row larger than the slot error = %v, want %v", err, engine.ErrRowTooLarge)
+			}
+		})
+	}
+}
+
+func TestNotNull(t *testing.T) {
+	table, err := btree.DbOpen(engine.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	ctx := context.Background()
+	if _, err := engine.Exec(ctx, []byte("alter table users add age not null default 18"), table); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command  string
+		want     error
+		position int
+	}{
+		{command: "insert NULL user1 person1@example.com", want: engine.ErrNotNull, position: 7},
+		{command: "insert 1 user1 person1@example.com NULL", want: engine.ErrNotNull, position: 35},
+		{command: "insert 1 NULL NULL"},
+		{command: "insert 2 user2 person2@example.com 30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			_, err := engine.Exec(ctx, []byte(tt.command), table)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Exec() error = %v, want %v", err, tt.want)
+			}
+			var e *engine.Error
+			if errors.As(err, &e) && e.Position != tt.position {
+				t.Errorf("Exec() error at %d, want %d", e.Position, tt.position)
+			}
+		})
+	}
+
+	result, err := engine.Exec(ctx, []byte("select"), table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rowsOf(result), "(1, NULL, NULL, 18)\n(2, user2, person2@example.com, 30)"; got != want {
+		t.Errorf("Exec(select) = %q, want %q", got, want)
+	}
+}
+
+func TestAlterTableError(t *testing.T) {
+	table, err := btree.DbOpen(engine.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	ctx := context.Background()
+	if _, err := engine.Exec(ctx, []byte("alter table users add age default 0"), table); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command string
+		want    error
+	}{
+		{command: "alter table users", want: engine.ErrSyntax},
+		{command: "alter table people add age", want: engine.ErrSyntax},
+		{command: "alter table users add 1age", want: engine.ErrSyntax},
+		{command: "alter table users add Age", want: engine.ErrSyntax},
+		{command: "alter table users add nick not null", want: engine.ErrSyntax},
+		{command: "alter table users add nick default ?", want: engine.ErrSyntax},
+		{command: "alter table users add nick unique", want: engine.ErrSyntax},
+		{command: "alter table users add nick default " + strings.Repeat("a", 256), want: engine.ErrStringTooLong},
+		{command: "insert 1 user1 person1@example.com 20 21", want: engine.ErrSyntax},
+		{command: "select where nick = 1", want: engine.ErrSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if _, err := engine.Exec(ctx, []byte(tt.command), table); !errors.Is(err, tt.want) {
+				t.Errorf("Exec() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlterTableUnsupported(t *testing.T) {
+	table, err := btree.DbOpen(engine.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	// only the methods of engine.Storage, so it's not an engine.Schemaer
+	storage := struct{ engine.Storage }{table}
+	_, err = engine.Exec(context.Background(), []byte("alter table users add age"), storage)
+	if !errors.Is(err, engine.ErrUnrecognizedStatement) || !strings.Contains(err.Error(), "doesn't support ALTER TABLE") {
+		t.Errorf("Exec() error = %v, want %v", err, engine.ErrUnrecognizedStatement)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	// arraylike has rows in more than a page, btree only in its root as it
+	// doesn't select from internal nodes
+	for name, numRows := range map[string]int{"arraylike": 10, "btree": 6} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			storage, err := engine.OpenStorage(name, filepath.Join(dir, "test.db"), engine.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer storage.Close()
+
+			ctx := context.Background()
+			exec := func(command string) {
+				t.Helper()
+				if err := engine.Process([]byte(command), storage, nil); err != nil {
+					t.Fatalf("Process(%q) error = %v", command, err)
+				}
+			}
+			// the rows are only in the cache
+			for i := 1; i < numRows; i++ {
+				exec(fmt.Sprintf("insert %d user%d person%d@example.com", i, i, i))
+			}
+			exec("alter table users add column age default 18")
+			exec(fmt.Sprintf("insert %d user%d person%d@example.com 30", numRows, numRows, numRows))
+			want, err := engine.Exec(ctx, []byte("select"), storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the open DB file isn't replaced by its copy
+			err = engine.Process([]byte(".backup "+dir+"/./test.db"), storage, nil)
+			if !errors.Is(err, engine.ErrCommandFailure) || !errors.Is(err, vfs.ErrSameFile) {
+				t.Errorf(".backup of the DB file itself error = %v, want %v", err, vfs.ErrSameFile)
+			}
+
+			backup := filepath.Join(dir, "backup.db")
+			exec(".backup " + backup)
+			// the DB is still open and changes after the backup aren't in it
+			exec("alter table users add column nick")
+
+			if files, _ := filepath.Glob(backup + "*"); len(files) != 1 {
+				t.Errorf("Files of the backup = %v, want only %s", files, backup)
+			}
+			copied, err := engine.OpenStorage(name, backup, engine.Options{ReadOnly: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer copied.Close()
+			got, err := engine.Exec(ctx, []byte("select"), copied)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got.Columns, ",") != strings.Join(want.Columns, ",") {
+				t.Errorf("Columns of the backup = %v, want %v", got.Columns, want.Columns)
+			}
+			if g, w := rowsOf(got), rowsOf(want); g != w {
+				t.Errorf("Rows of the backup = %q, want %q", g, w)
+			}
+		})
+	}
+}
+
+func TestBackupFailure(t *testing.T) {
+	fs := vfs.NewMemory()
+	faulty := &vfs.Faulty{VFS: fs}
+	table, err := btree.DbOpenWithOptions("test.db", engine.Options{VFS: faulty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if err := table.Insert(engine.NewRow(1, engine.NewText("user1"), engine.NewText("person1@example.com"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Backup("test.db"); !errors.Is(err, vfs.ErrSameFile) {
+		t.Errorf("Backup() of the DB file itself error = %v, want %v", err, vfs.ErrSameFile)
+	}
+	if err := table.Backup("backup.db"); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Insert(engine.NewRow(2, engine.NewText("user2"), engine.NewText("person2@example.com"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed backup leaves the previous one as it was
+	faulty.FailWrite = faulty.Writes() + 2
+	if err := table.Backup("backup.db"); !errors.Is(err, vfs.ErrFault) {
+		t.Fatalf("Backup() error = %v, want %v", err, vfs.ErrFault)
+	}
+	if _, err := fs.Open("backup.db.tmp", true); err == nil {
+		t.Errorf("Open() of the temporary file of a failed backup error = nil")
+	}
+	backup, err := btree.DbOpenWithOptions("backup.db", engine.Options{VFS: fs, ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	if rows, err := backup.Select(context.Background()); err != nil || len(rows) != 1 {
+		t.Errorf("Select() of the backup = %d rows, %v, want 1 row", len(rows), err)
+	}
+}
+
+// rowsOf returns the rows of result, a row per line.
+func rowsOf(result *engine.Result) string {
+	var rows []string
+	for result.Next() {
+		rows = append(rows, result.Row().String())
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+func TestDumpRead(t *testing.T) {
+	for _, name := range []string{"arraylike", "btree"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			storage, err := engine.OpenStorage(name, filepath.Join(dir, "test.db"), engine.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer storage.Close()
+
+			ctx := context.Background()
+			for _, command := range []string{
+				"insert 1 'it''s' -2.5",
+				"insert 2 NULL x'00ff'",
+				"alter table users add column age not null default 18",
+				"alter table users add column nick default 'no one'",
+				"insert 3 user3 person3@example.com 42 NULL",
+				"insert 4294967295 '' 0.125 -7",
+			} {
+				if _, err := engine.Exec(ctx, []byte(command), storage); err != nil {
+					t.Fatalf("Exec(%q) error = %v", command, err)
+				}
+			}
+
+			script := filepath.Join(dir, "dump.sql")
+			if err := engine.Process([]byte(".dump "+script), storage, nil); err != nil {
+				t.Fatalf("Process(.dump) error = %v", err)
+			}
+			replayed, err := engine.OpenStorage(name, filepath.Join(dir, "replayed.db"), engine.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer replayed.Close()
+			if err := engine.Process([]byte(".read "+script), replayed, nil); err != nil {
+				t.Fatalf("Process(.read) error = %v", err)
+			}
+
+			want, err := engine.Exec(ctx, []byte("select"), storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := engine.Exec(ctx, []byte("select"), replayed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got.Columns, ",") != strings.Join(want.Columns, ",") {
+				t.Errorf("Columns of the replayed DB = %v, want %v", got.Columns, want.Columns)
+			}
+			if g, w := rowsOf(got), rowsOf(want); g != w {
+				t.Errorf("Rows of the replayed DB = %q, want %q", g, w)
+			}
+			if !reflect.DeepEqual(engine.SchemaOf(replayed), engine.SchemaOf(storage)) {
+				t.Errorf("SchemaOf() the replayed DB = %v, want %v", engine.SchemaOf(replayed), engine.SchemaOf(storage))
+			}
+		})
+	}
+}
+
+func TestReadItself(t *testing.T) {
+	table, err := btree.DbOpen(engine.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	script := filepath.Join(t.TempDir(), "script.sql")
+	if err := os.WriteFile(script, []byte(".read "+script+"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Process([]byte(".read "+script), table, nil); !errors.Is(err, engine.ErrCommandFailure) || !strings.Contains(err.Error(), "nested deeper") {
+		t.Errorf("Process(.read) of a script which reads itself error = %v, want %v", err, engine.ErrCommandFailure)
+	}
+}
@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is the failure of a statement or a meta command. Status classifies the
+// failure and Err, if any, is the underlying cause. Errors match the sentinel
+// of their status by errors.Is, e.g. errors.Is(err, ErrDuplicateKey).
+type Error struct {
+	Status ExecutionStatus
+	// Statement is the failed statement and Position is the byte offset of
+	// the failure in it, or -1 when the failure isn't about a part of it.
+	Statement string
+	Position  int
+	Err       error
+}
+
+var (
+	ErrUnrecognizedStatement = &Error{Status: PrepareUnrecognizedStatement}
+	ErrSyntax                = &Error{Status: PrepareSyntaxError}
+	ErrStringTooLong         = &Error{Status: PrepareStringTooLong}
+	ErrNegativeId            = &Error{Status: PrepareNegativeId}
+
+	ErrTableFull    = &Error{Status: ExecuteTableFull}
+	ErrTableEmpty   = &Error{Status: ExecuteTableEmpty}
+	ErrRowNotFound  = &Error{Status: ExecuteRowNotFound}
+	ErrPageFetch    = &Error{Status: ExecutePageFetchError}
+	ErrDuplicateKey = &Error{Status: ExecuteDuplicateKey}
+	ErrLocked       = &Error{Status: ExecuteLocked}
+	ErrReadOnly     = &Error{Status: ExecuteReadOnly}
+	ErrNotNull      = &Error{Status: ExecuteNotNull}
+	ErrCorrupt      = &Error{Status: ExecuteCorrupt}
+	ErrRowTooLarge  = &Error{Status: ExecuteRowTooLarge}
+
+	ErrUnrecognizedCommand = &Error{Status: MetaUnrecognizedCommand}
+	ErrCommandFailure      = &Error{Status: MetaCommandFailure}
+	ErrExit                = &Error{Status: MetaExit}
+
+	ErrExitFailure    = &Error{Status: ExitFailure}
+	ErrNotImplemented = &Error{Status: TODO}
+)
+
+var statusMessages = map[ExecutionStatus]string{
+	PrepareUnrecognizedStatement: "Unrecognized statement",
+	PrepareSyntaxError:           "Syntax error",
+	PrepareStringTooLong:         "String is too long",
+	PrepareNegativeId:            "ID must be positive",
+	ExecuteTableFull:             "Table full",
+	ExecuteTableEmpty:            "Table empty",
+	ExecuteRowNotFound:           "Row not found",
+	ExecutePageFetchError:        "Unable to fetch page",
+	ExecuteDuplicateKey:          "Duplicate key",
+	ExecuteLocked:                "Database is locked",
+	ExecuteReadOnly:              "Database is read only",
+	ExecuteNotNull:               "NOT NULL constraint failed",
+	ExecuteCorrupt:               "Database disk image is malformed",
+	ExecuteRowTooLarge:           "Row is too large",
+	MetaUnrecognizedCommand:      "Unrecognized command",
+	MetaCommandFailure:           "Command failed",
+	MetaExit:                     "Exit requested",
+	ExitFailure:                  "Failure",
+	TODO:                         "Not implemented",
+}
+
+// NewError returns an error of the given status caused by err.
+func NewError(status ExecutionStatus, err error) *Error {
+	return &Error{Status: status, Err: err}
+}
+
+// syntaxError returns an error of the given status pointing to pos of the
+// statement.
+func syntaxError(status ExecutionStatus, statement []byte, pos int) *Error {
+	return &Error{Status: status, Statement: string(statement), Position: pos}
+}
+
+func (e *Error) Error() string {
+	message, ok := statusMessages[e.Status]
+	if !ok {
+		message = fmt.Sprintf("Status 0x%X", e.Status)
+	}
+	if e.Statement != "" && e.Position >= 0 {
+		message = fmt.Sprintf("%s at position %d of `%s`", message, e.Position, e.Statement)
+	} else if e.Statement != "" {
+		message = fmt.Sprintf("%s in `%s`", message, e.Statement)
+	}
+	if e.Err != nil {
+		message = fmt.Sprintf("%s: %s", message, e.Err)
+	}
+
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel of the same status.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+
+	return ok && t.Status == e.Status
+}
+
+// StatusOf returns the status of err, or ExitFailure if it's not an Error.
+func StatusOf(err error) ExecutionStatus {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Status
+	}
+
+	return ExitFailure
+}
+
+// withStatement attaches the failed statement to err if it doesn't have one.
+func withStatement(err error, statement []byte) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return &Error{Status: ExitFailure, Statement: string(statement), Position: -1, Err: err}
+	}
+	if e.Statement != "" {
+		return err
+	}
+
+	withStatement := *e
+	withStatement.Statement = string(statement)
+	withStatement.Position = -1
+
+	return &withStatement
+}
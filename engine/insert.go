@@ -2,21 +2,119 @@ package engine
 
 import (
 	"fmt"
+	"math"
 )
 
-func prepareInsert(command []byte, statement *Statement) ExecutionStatus {
-	row := Row{}
-	n, err := fmt.Sscanf(string(command), "insert %d %s %s", &(row.Id), &(row.Username), &(row.Email))
-	if n < 3 || err != nil {
-		return PrepareSyntaxError
+func prepareInsert(command []byte, statement *Statement) error {
+	tokens, err := tokenize(command)
+	if err != nil {
+		return err
 	}
-	if len(row.Email) > 255 || len(row.Username) > 255 {
-		return PrepareStringTooLong
+	// values of the columns which are added by ALTER TABLE may be left out
+	if len(tokens) < len(Schema)+1 {
+		return syntaxError(PrepareSyntaxError, command, len(command))
 	}
-	if row.Id < 0 {
-		return PrepareNegativeId
+	if tokens[0].text != string(StatementInsert) {
+		return syntaxError(PrepareSyntaxError, command, tokens[0].pos)
 	}
-	statement.RowToInsert = &row
 
-	return PrepareSuccess
+	for _, t := range tokens[1:] {
+		f, ok := statement.params.parseField(t)
+		if !ok {
+			return syntaxError(PrepareSyntaxError, command, t.pos)
+		}
+		statement.fields = append(statement.fields, f)
+	}
+
+	// literals are validated while preparing, placeholders on every execution
+	for i, f := range statement.fields {
+		if f.param != 0 {
+			continue
+		}
+		if _, err := statement.checkField(i, f, f.value); err != nil {
+			return err
+		}
+	}
+
+	if statement.NumParams() == 0 {
+		row, err := statement.bindInsert()
+		if err != nil {
+			return err
+		}
+		statement.RowToInsert = row
+	}
+
+	return nil
+}
+
+// bindInsert resolves the row of an insert from its literals and bound values.
+func (s *Statement) bindInsert() (*Row, error) {
+	values := make([]Value, len(s.fields))
+	for i, f := range s.fields {
+		v, err := s.bindValue(f)
+		if err != nil {
+			return nil, err
+		}
+		if values[i], err = s.checkField(i, f, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewRow(uint32(values[idColumn].Int()), values[idColumn+1:]...), nil
+}
+
+// checkField validates the value of the i-th column of an insert. The id is a
+// NOT NULL INTEGER in the range of keys and TEXTs and BLOBs of the other
+// columns are at most 255 bytes.
+func (s *Statement) checkField(i int, f field, v Value) (Value, error) {
+	if v.IsNull() && i == idColumn {
+		return v, &Error{Status: ExecuteNotNull, Statement: s.String(), Position: f.pos, Err: fmt.Errorf("Column %s", Schema[i].Name)}
+	}
+	if i != idColumn {
+		if v.Len() > 255 {
+			return v, syntaxError(PrepareStringTooLong, s.text, f.pos)
+		}
+		return v, nil
+	}
+
+	// the same affinity as in `where id = ...`, e.g. '1' is the id 1
+	id := v.integerAffinity()
+	if id.Type() != ValueInteger {
+		return v, &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: f.pos, Err: fmt.Errorf("ID must be an INTEGER, got %s", v.Type())}
+	}
+	if id.Int() < 0 {
+		return v, syntaxError(PrepareNegativeId, s.text, f.pos)
+	}
+	if id.Int() > math.MaxUint32 {
+		return v, &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: f.pos, Err: fmt.Errorf("ID %d is out of range", id.Int())}
+	}
+
+	return id, nil
+}
+
+// completeRow checks the row of an insert against the columns of the table and
+// returns a copy of it in which the columns which are left out have their
+// defaults.
+func (s *Statement) completeRow(schema []Column, inserted *Row) (*Row, error) {
+	if len(s.fields) > len(schema) {
+		f := s.fields[len(schema)]
+		return nil, &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: f.pos, Err: fmt.Errorf("Table %s has %d columns", TableName, len(schema))}
+	}
+
+	row := NewRow(inserted.Id, append([]Value(nil), inserted.Fields...)...)
+	for _, column := range schema[len(row.Fields)+1:] {
+		row.Fields = append(row.Fields, column.Default)
+	}
+	for i, v := range row.Values() {
+		if !v.IsNull() || !schema[i].NotNull {
+			continue
+		}
+		pos := -1
+		if i < len(s.fields) {
+			pos = s.fields[i].pos
+		}
+		return nil, &Error{Status: ExecuteNotNull, Statement: s.String(), Position: pos, Err: fmt.Errorf("Column %s", schema[i].Name)}
+	}
+
+	return row, nil
 }
@@ -1,22 +1,95 @@
 package engine
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 )
 
-func processMeta(command []byte, storage Storage) ExecutionStatus {
+// maxReadDepth is how deep `.read` commands are nested in scripts, so a
+// script which reads itself fails instead of overflowing the stack.
+const maxReadDepth = 32
+
+func processMeta(command []byte, storage Storage, writer ResultWriter, depth int) error {
 	if Equal(command, ".exit") {
-		closeStorage(storage)
-		os.Exit(0)
+		return ErrExit
+	}
+
+	name, arg := splitMeta(command)
+	switch {
+	case Equal(name, ".dump"):
+		return dumpTo(arg, storage)
+	case Equal(name, ".read"):
+		return readFrom(arg, storage, writer, depth)
+	case Equal(name, ".backup"):
+		return backupTo(arg, storage)
+	}
+	if w, ok := writer.(MetaWriter); ok {
+		if handled, err := w.ExecuteMeta(command); handled {
+			return err
+		}
 	}
 
 	return storage.ExecuteMeta(command)
 }
 
-func closeStorage(storage Storage) {
-	if status, err := storage.Close(); err != nil {
-		fmt.Println(err)
-		os.Exit(int(status))
+// splitMeta separates a meta command from its (optional) argument.
+func splitMeta(command []byte) ([]byte, string) {
+	name, arg, _ := bytes.Cut(command, []byte(" "))
+
+	return name, string(bytes.TrimSpace(arg))
+}
+
+func dumpTo(path string, storage Storage) error {
+	if path == "" {
+		return Dump(os.Stdout, storage)
 	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return NewError(MetaCommandFailure, err)
+	}
+
+	if err := Dump(f, storage); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return NewError(MetaCommandFailure, err)
+	}
+
+	return nil
+}
+
+func readFrom(path string, storage Storage, writer ResultWriter, depth int) error {
+	if path == "" {
+		return ErrSyntax
+	}
+	if depth >= maxReadDepth {
+		return NewError(MetaCommandFailure, fmt.Errorf("Scripts are nested deeper than %d", maxReadDepth))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return NewError(MetaCommandFailure, err)
+	}
+	defer f.Close()
+
+	return read(f, storage, writer, depth+1)
+}
+
+func backupTo(path string, storage Storage) error {
+	backuper, ok := storage.(Backuper)
+	if !ok {
+		return ErrUnrecognizedCommand
+	}
+	if path == "" {
+		return ErrSyntax
+	}
+
+	if err := backuper.Backup(path); err != nil {
+		return NewError(MetaCommandFailure, err)
+	}
+
+	return nil
 }
@@ -0,0 +1,31 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// csvWriter prints rows as RFC 4180 comma separated values.
+type csvWriter struct {
+	w       io.Writer
+	headers bool
+}
+
+func (c *csvWriter) WriteRows(columns []string, rows []*engine.Row) error {
+	w := csv.NewWriter(c.w)
+	if c.headers {
+		if err := w.Write(columns); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		if err := w.Write(formatValues(row.Values())); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
@@ -0,0 +1,67 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// jsonWriter prints rows as an array of JSON objects, or when lines is set, as
+// one JSON object per line. Headers are always part of the objects.
+type jsonWriter struct {
+	w     io.Writer
+	lines bool
+}
+
+func (j *jsonWriter) WriteRows(columns []string, rows []*engine.Row) error {
+	objects := make([][]byte, len(rows))
+	for i, row := range rows {
+		object, err := jsonObject(columns, row.Values())
+		if err != nil {
+			return err
+		}
+		objects[i] = object
+	}
+
+	if j.lines {
+		for _, object := range objects {
+			if _, err := fmt.Fprintf(j.w, "%s\n", object); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	_, err := fmt.Fprintf(j.w, "[%s]\n", bytes.Join(objects, []byte(",\n")))
+
+	return err
+}
+
+// jsonObject encodes a row as an object which keeps the order of columns.
+func jsonObject(columns []string, values []engine.Value) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, column := range columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
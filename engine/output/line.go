@@ -0,0 +1,38 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// lineWriter prints each field on its own line as `column = value` and
+// separates rows by an empty line.
+type lineWriter struct {
+	w io.Writer
+}
+
+func (l *lineWriter) WriteRows(columns []string, rows []*engine.Row) error {
+	width := 0
+	for _, column := range columns {
+		if len(column) > width {
+			width = len(column)
+		}
+	}
+
+	for i, row := range rows {
+		if i > 0 {
+			if _, err := fmt.Fprintln(l.w); err != nil {
+				return err
+			}
+		}
+		for j, value := range formatValues(row.Values()) {
+			if _, err := fmt.Fprintf(l.w, "%*s = %s\n", width, columns[j], value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+type Mode string
+
+const (
+	ModeTuple Mode = "tuple"
+	ModeTable Mode = "table"
+	ModeCSV   Mode = "csv"
+	ModeJSON  Mode = "json"
+	ModeJSONL Mode = "jsonl"
+	ModeLine  Mode = "line"
+)
+
+// Modes lists the supported output modes.
+var Modes = []Mode{ModeTuple, ModeTable, ModeCSV, ModeJSON, ModeJSONL, ModeLine}
+
+// New returns a writer which renders rows into w using the given mode. When
+// headers is set, the column names are printed before the rows.
+func New(mode Mode, w io.Writer, headers bool) (engine.ResultWriter, error) {
+	switch mode {
+	case ModeTuple:
+		return &tupleWriter{w: w, headers: headers}, nil
+	case ModeTable:
+		return &tableWriter{w: w, headers: headers}, nil
+	case ModeCSV:
+		return &csvWriter{w: w, headers: headers}, nil
+	case ModeJSON:
+		return &jsonWriter{w: w}, nil
+	case ModeJSONL:
+		return &jsonWriter{w: w, lines: true}, nil
+	case ModeLine:
+		return &lineWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("Unknown output mode %s, available modes: %s", mode, modeNames())
+	}
+}
+
+func modeNames() string {
+	names := make([]string, len(Modes))
+	for i, mode := range Modes {
+		names[i] = string(mode)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func formatValues(values []engine.Value) []string {
+	result := make([]string, len(values))
+	for i, value := range values {
+		result[i] = value.String()
+	}
+
+	return result
+}
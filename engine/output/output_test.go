@@ -0,0 +1,81 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+func TestNew(t *testing.T) {
+	rows := []*engine.Row{
+		{Id: 1, Fields: []engine.Value{engine.NewText("meysampg"), engine.NewText("myemail@domain.com")}},
+		{Id: 2, Fields: []engine.Value{engine.NewText("a,b"), engine.NewText("b@domain.com")}},
+	}
+	tests := []struct {
+		name    string
+		mode    Mode
+		headers bool
+		want    string
+	}{
+		{
+			name: "tuple",
+			mode: ModeTuple,
+			want: "(1, meysampg, myemail@domain.com)\n(2, a,b, b@domain.com)\n",
+		},
+		{
+			name:    "table with headers",
+			mode:    ModeTable,
+			headers: true,
+			want: "+----+----------+--------------------+\n" +
+				"| id | username | email              |\n" +
+				"+----+----------+--------------------+\n" +
+				"| 1  | meysampg | myemail@domain.com |\n" +
+				"| 2  | a,b      | b@domain.com       |\n" +
+				"+----+----------+--------------------+\n",
+		},
+		{
+			name:    "csv with headers",
+			mode:    ModeCSV,
+			headers: true,
+			want:    "id,username,email\n1,meysampg,myemail@domain.com\n2,\"a,b\",b@domain.com\n",
+		},
+		{
+			name: "json",
+			mode: ModeJSON,
+			want: "[{\"id\":1,\"username\":\"meysampg\",\"email\":\"myemail@domain.com\"},\n" +
+				"{\"id\":2,\"username\":\"a,b\",\"email\":\"b@domain.com\"}]\n",
+		},
+		{
+			name: "jsonl",
+			mode: ModeJSONL,
+			want: "{\"id\":1,\"username\":\"meysampg\",\"email\":\"myemail@domain.com\"}\n" +
+				"{\"id\":2,\"username\":\"a,b\",\"email\":\"b@domain.com\"}\n",
+		},
+		{
+			name: "line",
+			mode: ModeLine,
+			want: "      id = 1\nusername = meysampg\n   email = myemail@domain.com\n\n" +
+				"      id = 2\nusername = a,b\n   email = b@domain.com\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			w, err := New(tt.mode, &b, tt.headers)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := w.WriteRows(engine.Columns, rows); err != nil {
+				t.Fatalf("WriteRows() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("WriteRows() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := New("unknown", &bytes.Buffer{}, false); err == nil {
+		t.Errorf("New() with unknown mode, want error")
+	}
+}
@@ -0,0 +1,80 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// tableWriter prints rows as a table with aligned columns.
+type tableWriter struct {
+	w       io.Writer
+	headers bool
+}
+
+func (t *tableWriter) WriteRows(columns []string, rows []*engine.Row) error {
+	cells := make([][]string, 0, len(rows)+1)
+	if t.headers {
+		cells = append(cells, columns)
+	}
+	for _, row := range rows {
+		cells = append(cells, formatValues(row.Values()))
+	}
+	if len(cells) == 0 {
+		return nil
+	}
+
+	widths := make([]int, len(columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	separator := tableSeparator(widths)
+	if _, err := fmt.Fprintln(t.w, separator); err != nil {
+		return err
+	}
+	for i, line := range cells {
+		if _, err := fmt.Fprintln(t.w, tableLine(widths, line)); err != nil {
+			return err
+		}
+		if t.headers && i == 0 {
+			if _, err := fmt.Fprintln(t.w, separator); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(t.w, separator)
+
+	return err
+}
+
+func tableSeparator(widths []int) string {
+	var b strings.Builder
+	b.WriteString("+")
+	for _, width := range widths {
+		b.WriteString(strings.Repeat("-", width+2))
+		b.WriteString("+")
+	}
+
+	return b.String()
+}
+
+func tableLine(widths []int, cells []string) string {
+	var b strings.Builder
+	b.WriteString("|")
+	for i, cell := range cells {
+		b.WriteString(" ")
+		b.WriteString(cell)
+		b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+1))
+		b.WriteString("|")
+	}
+
+	return b.String()
+}
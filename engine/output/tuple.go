@@ -0,0 +1,31 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// tupleWriter prints each row as `(1, username, email)`.
+type tupleWriter struct {
+	w       io.Writer
+	headers bool
+}
+
+func (t *tupleWriter) WriteRows(columns []string, rows []*engine.Row) error {
+	if t.headers {
+		if _, err := fmt.Fprintf(t.w, "(%s)\n", strings.Join(columns, ", ")); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(t.w, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
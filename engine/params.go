@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// params are the placeholders of a statement and their bound values.
+type params struct {
+	values []boundValue
+	names  map[string]int
+}
+
+type boundValue struct {
+	value interface{}
+	bound bool
+}
+
+// field is a value of a statement which is either a literal or a placeholder.
+type field struct {
+	token
+	// param is the index of the placeholder, or 0 for literals.
+	param int
+	// value is the parsed value of a literal
+	value Value
+}
+
+// parseField recognizes `?`, `$N` and `:name` placeholders and parses the
+// other tokens as literals. `?` takes the index after the largest one which is
+// seen so far, like SQLite.
+func (p *params) parseField(t token) (field, bool) {
+	switch {
+	case t.text == "?":
+		return p.field(t, len(p.values)+1), true
+	case len(t.text) > 1 && t.text[0] == '$':
+		index, err := strconv.Atoi(t.text[1:])
+		if err != nil || index < 1 {
+			return field{}, false
+		}
+		return p.field(t, index), true
+	case len(t.text) > 1 && t.text[0] == ':':
+		name := t.text[1:]
+		if index, ok := p.names[name]; ok {
+			return p.field(t, index), true
+		}
+		if p.names == nil {
+			p.names = make(map[string]int)
+		}
+		p.names[name] = len(p.values) + 1
+		return p.field(t, p.names[name]), true
+	}
+
+	value, ok := ParseLiteral(t.text)
+
+	return field{token: t, value: value}, ok
+}
+
+func (p *params) field(t token, index int) field {
+	for len(p.values) < index {
+		p.values = append(p.values, boundValue{})
+	}
+
+	return field{token: t, param: index}
+}
+
+func errParamIndex(index int) error {
+	return fmt.Errorf("No parameter at index %d", index)
+}
+
+func errParamName(name string) error {
+	return fmt.Errorf("No parameter named :%s", name)
+}
+
+// bound returns the value of the field's placeholder.
+func (s *Statement) bound(f field) (interface{}, error) {
+	v := s.params.values[f.param-1]
+	if !v.bound {
+		return nil, &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: f.pos, Err: fmt.Errorf("Parameter %s is not bound", f.text)}
+	}
+
+	return v.value, nil
+}
+
+// bindValue returns the value of a literal or the bound value of a
+// placeholder.
+func (s *Statement) bindValue(f field) (Value, error) {
+	if f.param == 0 {
+		return f.value, nil
+	}
+
+	value, err := s.bound(f)
+	if err != nil {
+		return Null, err
+	}
+	v, err := ValueOf(value)
+	if err != nil {
+		return Null, &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: f.pos, Err: fmt.Errorf("Parameter %s: %w", f.text, err)}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultEngine is the engine used when none is given.
+const DefaultEngine = "arraylike"
+
+// Capabilities are what an engine supports beyond the Storage interface.
+type Capabilities struct {
+	// Ranges is true when rows are kept ordered by id, so a range of ids can
+	// be scanned without reading the whole table.
+	Ranges bool
+	// Transactions is true when changes can be committed or rolled back as a
+	// whole.
+	Transactions bool
+	// MetaCommands are the meta commands handled by the engine's ExecuteMeta.
+	MetaCommands []string
+}
+
+// OpenFunc opens the DB file at path.
+type OpenFunc func(path string, opts Options) (Storage, error)
+
+// EngineInfo describes a registered engine.
+type EngineInfo struct {
+	Name         string
+	Description  string
+	Capabilities Capabilities
+	Open         OpenFunc
+}
+
+// ErrEngineNotFound is the error of OpenStorage for names which aren't
+// registered.
+var ErrEngineNotFound = errors.New("Engine not found")
+
+var (
+	enginesMu sync.RWMutex
+	engines   = make(map[string]EngineInfo)
+)
+
+// Register makes an engine available by its name, usually from the init
+// function of the storage package. Names are unique, like sql.Register it
+// panics if the name is already registered or info.Open is nil.
+func Register(info EngineInfo) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+
+	if info.Open == nil {
+		panic("engine: Register open func is nil")
+	}
+	if _, dup := engines[info.Name]; dup {
+		panic("engine: Register called twice for engine " + info.Name)
+	}
+	engines[info.Name] = info
+}
+
+// Engines returns the registered engines sorted by name.
+func Engines() []EngineInfo {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+
+	list := make([]EngineInfo, 0, len(engines))
+	for _, info := range engines {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+// LookupEngine returns the registered engine of the name.
+func LookupEngine(name string) (EngineInfo, bool) {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+
+	info, ok := engines[name]
+
+	return info, ok
+}
+
+// OpenStorage opens the DB file at path by the registered engine of the name,
+// or by DefaultEngine if the name is empty.
+func OpenStorage(name, path string, opts Options) (Storage, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+
+	info, ok := LookupEngine(name)
+	if !ok {
+		return nil, fmt.Errorf("%w, %s", ErrEngineNotFound, name)
+	}
+
+	return info.Open(path, opts)
+}
@@ -0,0 +1,41 @@
+package engine
+
+// ResultWriter renders the rows which are returned by a statement.
+type ResultWriter interface {
+	WriteRows(columns []string, rows []*Row) error
+}
+
+// MetaWriter is implemented by ResultWriters which have meta commands of their
+// own, like `.mode` of a CLI. They're run by Process in scripts too.
+type MetaWriter interface {
+	// ExecuteMeta runs command and returns true, or returns false if it's not
+	// a command of the writer.
+	ExecuteMeta(command []byte) (bool, error)
+}
+
+// Result is the outcome of a successful statement. Rows of a select are read
+// by calling Next until it returns false.
+type Result struct {
+	Columns      []string
+	RowsAffected int64
+
+	rows []*Row
+	row  *Row
+}
+
+// Next advances the result to the next row and reports whether there is one.
+func (r *Result) Next() bool {
+	if len(r.rows) == 0 {
+		r.row = nil
+		return false
+	}
+
+	r.row, r.rows = r.rows[0], r.rows[1:]
+
+	return true
+}
+
+// Row returns the current row of the result.
+func (r *Result) Row() *Row {
+	return r.row
+}
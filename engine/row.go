@@ -1,13 +1,65 @@
 package engine
 
-import "fmt"
+import "strings"
 
+// Column is a column of the table.
+type Column struct {
+	Name string
+	// NotNull rejects inserts of NULL into the column. Of the columns of a
+	// new table only the id is NOT NULL, added ones are declared NOT NULL by
+	// ALTER TABLE.
+	NotNull bool
+	// Default is the value of the column in rows which don't have it, i.e.
+	// rows which were inserted before the column was added.
+	Default Value
+}
+
+// Schema is the columns of a new table in the order they're stored. Columns
+// are added after them by ALTER TABLE.
+var Schema = []Column{
+	{Name: "id", NotNull: true},
+	{Name: "username"},
+	{Name: "email"},
+}
+
+// Columns are the names of the row fields in the order they're stored.
+var Columns = columnNames(Schema)
+
+func columnNames(schema []Column) []string {
+	names := make([]string, len(schema))
+	for i, column := range schema {
+		names[i] = column.Name
+	}
+
+	return names
+}
+
+// idColumn is the index of the key in Columns.
+const idColumn = 0
+
+// Row is a row of the table. Id is the key and Fields are the values of the
+// columns after it, which may be of any type.
 type Row struct {
-	Id       uint32
-	Username string
-	Email    string
+	Id     uint32
+	Fields []Value
+}
+
+// NewRow returns a row of id and the values of the other columns.
+func NewRow(id uint32, fields ...Value) *Row {
+	return &Row{Id: id, Fields: fields}
 }
 
 func (r *Row) String() string {
-	return fmt.Sprintf("(%d, %s, %s)", r.Id, r.Username, r.Email)
+	values := r.Values()
+	texts := make([]string, len(values))
+	for i, v := range values {
+		texts[i] = v.String()
+	}
+
+	return "(" + strings.Join(texts, ", ") + ")"
+}
+
+// Values returns the row fields in the order of Columns.
+func (r *Row) Values() []Value {
+	return append([]Value{NewInteger(int64(r.Id))}, r.Fields...)
 }
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TableName is the name of the table of a storage, which ALTER TABLE refers to.
+const TableName = "users"
+
+// Schemaer is implemented by storages which keep the schema of their table,
+// so columns can be added to it by ALTER TABLE.
+type Schemaer interface {
+	// Schema returns the columns of the table.
+	Schema() []Column
+	// AddColumn appends a column to the table. Stored rows aren't rewritten,
+	// they have the default of the column when they're read.
+	AddColumn(column Column) error
+}
+
+// SchemaOf returns the columns of the table of storage, which is Schema for
+// storages which aren't Schemaers.
+func SchemaOf(storage Storage) []Column {
+	if s, ok := storage.(Schemaer); ok {
+		return s.Schema()
+	}
+
+	return Schema
+}
+
+// ColumnNames returns the names of the columns of schema.
+func ColumnNames(schema []Column) []string {
+	return columnNames(schema)
+}
+
+func columnIndex(schema []Column, name string) int {
+	for i, column := range schema {
+		if strings.EqualFold(column.Name, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// SelectRows returns the rows of storage with a value for each of its columns,
+// unlike Storage.Select which returns rows as they're stored.
+func SelectRows(ctx context.Context, storage Storage) ([]*Row, error) {
+	return selectRows(ctx, storage, SchemaOf(storage))
+}
+
+// selectRows returns the rows of storage with a value for each column of
+// schema. Rows which were stored before columns were added take their
+// defaults.
+func selectRows(ctx context.Context, storage Storage, schema []Column) ([]*Row, error) {
+	rows, err := storage.Select(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if len(row.Fields) >= len(schema) {
+			return nil, NewError(ExecuteCorrupt, fmt.Errorf("Row %d has %d values, the table has %d columns", row.Id, len(row.Fields)+1, len(schema)))
+		}
+		for _, column := range schema[len(row.Fields)+1:] {
+			row.Fields = append(row.Fields, column.Default)
+		}
+	}
+
+	return rows, nil
+}
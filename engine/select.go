@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// predicate is the condition of `select where <column> <operator> <value>`,
+// `select where <column> is null` or `select where <column> is not null`.
+type predicate struct {
+	// column is resolved on execution, as columns may be added to the table
+	column   token
+	operator string
+	// operand is the value which the column is compared to, it's unused by
+	// the null tests
+	operand field
+}
+
+const (
+	operatorIsNull    = "is null"
+	operatorIsNotNull = "is not null"
+)
+
+// operators test the result of Compare for each comparison operator.
+var operators = map[string]func(c int) bool{
+	"=":  func(c int) bool { return c == 0 },
+	"==": func(c int) bool { return c == 0 },
+	"!=": func(c int) bool { return c != 0 },
+	"<>": func(c int) bool { return c != 0 },
+	"<":  func(c int) bool { return c < 0 },
+	"<=": func(c int) bool { return c <= 0 },
+	">":  func(c int) bool { return c > 0 },
+	">=": func(c int) bool { return c >= 0 },
+}
+
+func prepareSelect(command []byte, statement *Statement) error {
+	tokens, err := tokenize(command)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 1 {
+		return nil
+	}
+	if !strings.EqualFold(tokens[1].text, "where") {
+		return syntaxError(PrepareSyntaxError, command, tokens[1].pos)
+	}
+	if len(tokens) < 5 {
+		return syntaxError(PrepareSyntaxError, command, len(command))
+	}
+
+	where := &predicate{column: tokens[2]}
+	// the tokens after the operator
+	var rest []token
+	if strings.EqualFold(tokens[3].text, "is") {
+		words := []string{"is"}
+		for _, t := range tokens[4:] {
+			words = append(words, strings.ToLower(t.text))
+		}
+		where.operator = strings.Join(words, " ")
+		if where.operator != operatorIsNull && where.operator != operatorIsNotNull {
+			return syntaxError(PrepareSyntaxError, command, tokens[4].pos)
+		}
+	} else {
+		where.operator, rest = tokens[3].text, tokens[4:]
+		if _, ok := operators[where.operator]; !ok {
+			return syntaxError(PrepareSyntaxError, command, tokens[3].pos)
+		}
+		if len(rest) > 1 {
+			return syntaxError(PrepareSyntaxError, command, rest[1].pos)
+		}
+		var ok bool
+		if where.operand, ok = statement.params.parseField(rest[0]); !ok {
+			return syntaxError(PrepareSyntaxError, command, rest[0].pos)
+		}
+	}
+	statement.where = where
+
+	return nil
+}
+
+// filter returns the rows which match the where clause of the statement.
+func (s *Statement) filter(schema []Column, rows []*Row) ([]*Row, error) {
+	if s.where == nil {
+		return rows, nil
+	}
+
+	column := columnIndex(schema, s.where.column.text)
+	if column < 0 {
+		return nil, &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: s.where.column.pos, Err: fmt.Errorf("No column named %s", s.where.column.text)}
+	}
+
+	operand := Null
+	if _, ok := operators[s.where.operator]; ok {
+		var err error
+		if operand, err = s.bindValue(s.where.operand); err != nil {
+			return nil, err
+		}
+	}
+	// the id column has INTEGER affinity, e.g. `where id = '1'` matches 1
+	if column == idColumn {
+		operand = operand.integerAffinity()
+	}
+
+	var matched []*Row
+	for _, row := range rows {
+		if s.where.match(row.Values()[column], operand) {
+			matched = append(matched, row)
+		}
+	}
+
+	return matched, nil
+}
+
+// match compares a value of the column to the operand. Comparisons with NULL
+// are never true, like in SQL, only the null tests match NULL.
+func (p *predicate) match(v, operand Value) bool {
+	switch p.operator {
+	case operatorIsNull:
+		return v.IsNull()
+	case operatorIsNotNull:
+		return !v.IsNull()
+	}
+	if v.IsNull() || operand.IsNull() {
+		return false
+	}
+
+	return operators[p.operator](Compare(v, operand))
+}
@@ -2,6 +2,9 @@ package engine
 
 import (
 	"bytes"
+	"context"
+	"unicode"
+	"unicode/utf8"
 )
 
 type StatementType string
@@ -9,20 +12,206 @@ type StatementType string
 const (
 	StatementInsert StatementType = "insert"
 	StatementSelect StatementType = "select"
+	StatementAlter  StatementType = "alter"
 )
 
+// Statement is a parsed statement. Placeholders (`?`, `$1` or `:name`) in it
+// are bound to values by Bind and BindName, so one statement can be executed
+// many times without being parsed again.
+//
+// Only Exec is safe for concurrent use. Bind, BindName and ClearBindings change
+// the values of the statement, so they must be called before it's shared.
 type Statement struct {
-	Type        StatementType
+	Type StatementType
+	// RowToInsert is the row of an insert without placeholders. The row of
+	// one with placeholders is resolved on every execution instead.
 	RowToInsert *Row
+
+	text   []byte
+	fields []field
+	where  *predicate
+	// column is the column which is added by an alter
+	column *Column
+	params *params
 }
 
-func PrepareStatement(command []byte) (*Statement, ExecutionStatus) {
+// PrepareStatement parses command into a statement which can be bound and
+// executed repeatedly.
+func PrepareStatement(command []byte) (*Statement, error) {
+	statement := &Statement{text: command, params: &params{}}
 	if bytes.HasPrefix(command, []byte(StatementInsert)) {
-		statement := &Statement{Type: StatementInsert}
-		return statement, prepareInsert(command, statement)
+		statement.Type = StatementInsert
+		if err := prepareInsert(command, statement); err != nil {
+			return nil, err
+		}
+		return statement, nil
 	} else if bytes.HasPrefix(command, []byte(StatementSelect)) {
-		return &Statement{Type: StatementSelect}, PrepareSuccess
+		statement.Type = StatementSelect
+		if err := prepareSelect(command, statement); err != nil {
+			return nil, err
+		}
+		return statement, nil
+	} else if bytes.HasPrefix(command, []byte(StatementAlter)) {
+		statement.Type = StatementAlter
+		if err := prepareAlter(command, statement); err != nil {
+			return nil, err
+		}
+		return statement, nil
+	}
+
+	return nil, syntaxError(PrepareUnrecognizedStatement, command, 0)
+}
+
+// String returns the text of the statement.
+func (s *Statement) String() string {
+	return string(s.text)
+}
+
+// NumParams returns the number of parameters of the statement. Parameters are
+// indexed from 1 to NumParams.
+func (s *Statement) NumParams() int {
+	return len(s.params.values)
+}
+
+// ParamName returns the name of the `:name` parameter at index, or "" for
+// `?` and `$N` parameters.
+func (s *Statement) ParamName(index int) string {
+	for name, i := range s.params.names {
+		if i == index {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Bind sets the value of the parameter at index, counted from 1.
+func (s *Statement) Bind(index int, value interface{}) error {
+	if index < 1 || index > len(s.params.values) {
+		return &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: -1, Err: errParamIndex(index)}
+	}
+	s.params.values[index-1] = boundValue{value: value, bound: true}
+
+	return nil
+}
+
+// BindName sets the value of the `:name` parameter.
+func (s *Statement) BindName(name string, value interface{}) error {
+	index, ok := s.params.names[name]
+	if !ok {
+		return &Error{Status: PrepareSyntaxError, Statement: s.String(), Position: -1, Err: errParamName(name)}
+	}
+
+	return s.Bind(index, value)
+}
+
+// ClearBindings unsets the values of all parameters.
+func (s *Statement) ClearBindings() {
+	for i := range s.params.values {
+		s.params.values[i] = boundValue{}
+	}
+}
+
+// Exec executes the statement with its bound values against storage. It may be
+// called concurrently, as long as the values aren't bound meanwhile.
+func (s *Statement) Exec(ctx context.Context, storage Storage) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, withStatement(err, s.text)
+	}
+
+	switch s.Type {
+	case StatementInsert:
+		// the row is kept local, so concurrent executions don't race on it
+		row := s.RowToInsert
+		if s.NumParams() > 0 {
+			var err error
+			if row, err = s.bindInsert(); err != nil {
+				return nil, err
+			}
+		}
+		row, err := s.completeRow(SchemaOf(storage), row)
+		if err != nil {
+			return nil, err
+		}
+		if err := storage.Insert(row); err != nil {
+			return nil, withStatement(err, s.text)
+		}
+
+		return &Result{RowsAffected: 1}, nil
+	case StatementSelect:
+		schema := SchemaOf(storage)
+		rows, err := selectRows(ctx, storage, schema)
+		if err != nil {
+			return nil, withStatement(err, s.text)
+		}
+		if rows, err = s.filter(schema, rows); err != nil {
+			return nil, err
+		}
+
+		return &Result{Columns: ColumnNames(schema), rows: rows}, nil
+	case StatementAlter:
+		if err := s.addColumn(storage); err != nil {
+			return nil, err
+		}
+
+		return &Result{}, nil
+	}
+
+	return &Result{}, nil
+}
+
+// token is a space separated part of a statement and its byte offset. Quoted
+// literals, e.g. 'a b' or x'00ff', are one token with their spaces.
+type token struct {
+	text string
+	pos  int
+}
+
+func tokenize(command []byte) ([]token, error) {
+	var tokens []token
+	text := string(command)
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+
+		start := i
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if unicode.IsSpace(r) {
+				break
+			}
+			if r == '\'' && (i == start || i == start+1 && (text[start] == 'x' || text[start] == 'X')) {
+				end := closingQuote(text, i)
+				if end < 0 {
+					return nil, syntaxError(PrepareSyntaxError, command, start)
+				}
+				i = end + 1
+				continue
+			}
+			i += size
+		}
+		tokens = append(tokens, token{text: text[start:i], pos: start})
+	}
+
+	return tokens, nil
+}
+
+// closingQuote returns the offset of the quote which closes the one at start,
+// skipping doubled quotes, or -1 if it's not closed.
+func closingQuote(text string, start int) int {
+	for i := start + 1; i < len(text); i++ {
+		if text[i] != '\'' {
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '\'' {
+			i++
+			continue
+		}
+		return i
 	}
 
-	return nil, PrepareUnrecognizedStatement
+	return -1
 }
@@ -15,9 +15,16 @@ const (
 	ExecuteRowNotFound    ExecutionStatus = 0xB04
 	ExecutePageFetchError ExecutionStatus = 0xB05
 	ExecuteDuplicateKey   ExecutionStatus = 0xB06
+	ExecuteLocked         ExecutionStatus = 0xB07
+	ExecuteReadOnly       ExecutionStatus = 0xB08
+	ExecuteNotNull        ExecutionStatus = 0xB09
+	ExecuteCorrupt        ExecutionStatus = 0xB0A
+	ExecuteRowTooLarge    ExecutionStatus = 0xB0B
 
 	MetaCommandSuccess      ExecutionStatus = 0xC01
 	MetaUnrecognizedCommand ExecutionStatus = 0xC02
+	MetaCommandFailure      ExecutionStatus = 0xC03
+	MetaExit                ExecutionStatus = 0xC04
 
 	ExitFailure ExecutionStatus = 0xD01
 
@@ -1,9 +1,65 @@
 package engine
 
+import (
+	"context"
+	"time"
+)
+
+// MemoryPath opens a DB which is only kept in memory, with the same semantics
+// as a file of the engine. It's gone when the storage is closed.
+const MemoryPath = ":memory:"
+
+// Options configure how storages open DB files.
+type Options struct {
+	// ReadOnly opens the DB file with a shared lock, so several processes can
+	// read it at the same time. Writes fail with ErrReadOnly.
+	ReadOnly bool
+	// BusyTimeout is how long to wait for the lock of another process on the
+	// DB file before failing with ErrLocked.
+	BusyTimeout time.Duration
+	// VFS opens the DB file, nil is the file system of the OS.
+	VFS VFS
+	// MMap makes storages which support it, like btree, read the DB file
+	// through a memory mapping rather than copying pages into buffers.
+	MMap bool
+}
+
 type Storage interface {
-	Insert(row *Row) ExecutionStatus
-	Select() ([]*Row, ExecutionStatus)
-	Close() (ExecutionStatus, error)
+	Insert(row *Row) error
+	// Select returns all rows. It stops scanning and returns the error of ctx
+	// when ctx is done.
+	Select(ctx context.Context) ([]*Row, error)
+	Close() error
 	GetPager() Pager
-	ExecuteMeta(command []byte) ExecutionStatus
+	ExecuteMeta(command []byte) error
+}
+
+// Counter is implemented by storages which count their rows without reading
+// them.
+type Counter interface {
+	Count(ctx context.Context) (int, error)
+}
+
+// CountRows returns the number of rows of storage, by selecting them if it's
+// not a Counter.
+func CountRows(ctx context.Context, storage Storage) (int, error) {
+	if c, ok := storage.(Counter); ok {
+		return c.Count(ctx)
+	}
+
+	rows, err := storage.Select(ctx)
+
+	return len(rows), err
+}
+
+// Flusher is implemented by storages which write their changes into the DB
+// file without closing it.
+type Flusher interface {
+	Flush() error
+}
+
+// Backuper is implemented by storages which are able to copy a live DB into
+// another file without closing it.
+type Backuper interface {
+	Backup(dst string) error
 }
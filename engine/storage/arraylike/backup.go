@@ -0,0 +1,56 @@
+package arraylike
+
+import (
+	"fmt"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/schema"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
+)
+
+// Backup writes a consistent copy of the DB into dst. Pages are fetched through
+// the pager, so rows which are only in the cache are part of the copy too.
+func (t *Table) Backup(dst string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.legacy {
+		return schema.ErrLegacy
+	}
+	// the copy would replace the open file, whose later writes would be lost
+	same, err := vfs.SameFile(t.fs, t.Pager.File, dst)
+	if err != nil {
+		return err
+	}
+	if same {
+		return vfs.ErrSameFile
+	}
+
+	return vfs.WriteFile(t.fs, dst, t.copyPages)
+}
+
+func (t *Table) copyPages(f engine.File) error {
+	// the header page and every page of rows, of which only the used part of
+	// the last one is written like Close does
+	numPages := rowPageNum(t.NumRows)
+	for i := headerPageNum; i <= numPages; i++ {
+		size := PageSize
+		if i == numPages && i != headerPageNum {
+			size = t.NumRows % RowsPerPage * RowSize
+			if size == 0 {
+				break
+			}
+		}
+
+		page, err := t.Pager.GetPage(i)
+		if err != nil {
+			return err
+		}
+		offset := int64(i) * int64(PageSize)
+		if n, err := vfs.WriteAt(f, page[:size], offset); err != nil {
+			return fmt.Errorf("Error writing backup page %d at offset %d, wrote %d of %d bytes: %w", i, offset, n, size, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package arraylike
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/utils"
+)
+
+// Legacy files don't have a header page and their rows have slots of 514
+// bytes from the page 0.
+const (
+	legacyRowSize     uint32 = 4 + 255 + 255
+	legacyRowsPerPage        = PageSize / legacyRowSize
+)
+
+// legacySelect returns the rows of a legacy file.
+func (t *Table) legacySelect(ctx context.Context) ([]*engine.Row, error) {
+	var result []*engine.Row
+	for rowNum := uint32(0); rowNum < t.NumRows; rowNum++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		page, err := t.Pager.GetPage(rowNum / legacyRowsPerPage)
+		if err != nil {
+			return nil, engine.NewError(engine.ExecutePageFetchError, err)
+		}
+		byteOffset := rowNum % legacyRowsPerPage * legacyRowSize
+		row, err := utils.Deserialize(binary.LittleEndian, page[byteOffset:byteOffset+legacyRowSize])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
@@ -3,30 +3,39 @@ package arraylike
 import (
 	"fmt"
 	"io"
-	"os"
+	"sync"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
 )
 
+// Pager caches the pages of the DB file. It's safe for concurrent use, but the
+// content of pages is guarded by the Table.
 type Pager struct {
-	FileDescriptor *os.File
-	FileLength     uint32
-	Pages          [][]byte
+	mu         sync.Mutex
+	File       engine.File
+	FileLength uint32
+	Pages      [][]byte
 }
 
-func NewPager(filename string) (*Pager, error) {
-	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0666)
+// NewPager opens the DB file by the VFS of opts and locks it, exclusively
+// unless it's opened as read only.
+func NewPager(filename string, opts engine.Options) (*Pager, error) {
+	file, err := vfs.Open(filename, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	fileLength, err := fd.Seek(0, io.SeekEnd)
+	fileLength, err := file.Size()
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
 
 	return &Pager{
-		FileDescriptor: fd,
-		FileLength:     uint32(fileLength),
-		Pages:          make([][]byte, TableMaxPage, TableMaxPage),
+		File:       file,
+		FileLength: uint32(fileLength),
+		Pages:      make([][]byte, TableMaxPage, TableMaxPage),
 	}, nil
 }
 
@@ -36,8 +45,11 @@ func (p *Pager) GetNumPages() uint32 {
 }
 
 func (p *Pager) GetPage(pageNum uint32) ([]byte, error) {
-	if pageNum > TableMaxPage {
-		return nil, fmt.Errorf("Tried to fetch page number out of bounds. %d > %d\n", pageNum, TableMaxPage)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pageNum >= TableMaxPage {
+		return nil, fmt.Errorf("Tried to fetch page number out of bounds. %d >= %d", pageNum, TableMaxPage)
 	}
 
 	// Here we have cache miss; fetch from file
@@ -51,13 +63,16 @@ func (p *Pager) GetPage(pageNum uint32) ([]byte, error) {
 
 		// if we already have page on disk, will try to load it. Otherwise, we don't have this page and can skip this step.
 		if pageNum < numPages {
-			_, err := p.FileDescriptor.Seek(int64(pageNum*PageSize), io.SeekStart)
-			if err != nil {
-				return nil, err
+			offset := int64(pageNum) * int64(PageSize)
+			n, err := vfs.ReadAt(p.File, page, offset)
+			// only the last page may be partial, the rest of it is zeroed
+			if err == io.EOF && pageNum == numPages-1 {
+				err = nil
+			} else if err == io.EOF {
+				err = io.ErrUnexpectedEOF
 			}
-			n, err := p.FileDescriptor.Read(page)
-			if n < 0 || err != nil {
-				return nil, fmt.Errorf("Error reading file: %d", n) // f*ck the errno :))
+			if err != nil {
+				return nil, fmt.Errorf("Error reading page %d at offset %d, read %d of %d bytes: %w", pageNum, offset, n, PageSize, err)
 			}
 		}
 
@@ -68,16 +83,20 @@ func (p *Pager) GetPage(pageNum uint32) ([]byte, error) {
 }
 
 func (p *Pager) Flush(pageNum int, size uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if p.Pages[pageNum] == nil {
 		return fmt.Errorf("Tried to flush null page")
 	}
 
-	if ret, err := p.FileDescriptor.Seek(int64(pageNum*int(PageSize)), io.SeekStart); err != nil || ret < 0 {
-		return fmt.Errorf("Error seeking: %d", ret)
+	offset := int64(pageNum) * int64(PageSize)
+	if n, err := vfs.WriteAt(p.File, p.Pages[pageNum][:size], offset); err != nil {
+		return fmt.Errorf("Error writing page %d at offset %d, wrote %d of %d bytes: %w", pageNum, offset, n, size, err)
 	}
-
-	if n, err := p.FileDescriptor.Write(p.Pages[pageNum][:size]); n < 0 || err != nil {
-		return fmt.Errorf("Error writing: %d", n)
+	// flushed pages are dropped from the cache and read back up to FileLength
+	if end := uint32(offset) + size; end > p.FileLength {
+		p.FileLength = end
 	}
 
 	return nil
@@ -0,0 +1,17 @@
+package arraylike
+
+import "github.com/meysampg/sqltut/engine"
+
+func init() {
+	engine.Register(engine.EngineInfo{
+		Name:        "arraylike",
+		Description: "Rows appended to fixed size pages in insertion order",
+		Open: func(path string, opts engine.Options) (engine.Storage, error) {
+			t, err := DbOpenWithOptions(path, opts)
+			if err != nil {
+				return nil, err
+			}
+			return t, nil
+		},
+	})
+}
@@ -1,36 +1,141 @@
 package arraylike
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
+	"sync"
 
 	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/schema"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
 	"github.com/meysampg/sqltut/engine/utils"
 )
 
 const (
 	PageSize     uint32 = 4096
 	TableMaxPage uint32 = 100
-	RowSize      uint32 = 4 + 255 + 255 // for the time being we assume string as a VARCHAR[255]
+	RowSize      uint32 = utils.RowSize
 	RowsPerPage  uint32 = PageSize / RowSize
-	TableMaxRows uint32 = RowsPerPage * TableMaxPage
+	TableMaxRows uint32 = RowsPerPage * (TableMaxPage - 1)
 )
 
+// headerPageNum is the page of the schema, rows start after it.
+const headerPageNum uint32 = 0
+
+// Table is safe for concurrent use. Statements which read rows run
+// concurrently and writes are exclusive.
 type Table struct {
-	NumRows uint32
-	Pager   *Pager
+	mu       sync.RWMutex
+	NumRows  uint32
+	Pager    *Pager
+	readOnly bool
+	// legacy is set for DB files which were written before the header page,
+	// they are only read.
+	legacy  bool
+	columns []engine.Column
+	// fs is the VFS of the DB file, backups are written by it too.
+	fs engine.VFS
 }
 
 func DbOpen(filename string) (*Table, error) {
-	pager, err := NewPager(filename)
+	return DbOpenWithOptions(filename, engine.Options{})
+}
+
+func DbOpenWithOptions(filename string, opts engine.Options) (*Table, error) {
+	pager, err := NewPager(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := openTable(pager, opts)
 	if err != nil {
+		pager.File.Close()
 		return nil, err
 	}
 
-	return &Table{
-		NumRows: pager.FileLength / RowSize,
-		Pager:   pager,
-	}, nil
+	return t, nil
+}
+
+// openTable reads the header page of the DB file, or initializes the header
+// page of a new one.
+func openTable(pager *Pager, opts engine.Options) (*Table, error) {
+	t := &Table{
+		Pager:    pager,
+		readOnly: opts.ReadOnly,
+		columns:  engine.Schema,
+		fs:       vfs.FS(opts),
+	}
+
+	header, err := pager.GetPage(headerPageNum)
+	if err != nil {
+		return nil, engine.NewError(engine.ExecutePageFetchError, err)
+	}
+	switch {
+	case pager.FileLength == 0:
+		return t, schema.Encode(header, t.columns)
+	case !schema.IsHeader(header):
+		t.legacy = true
+		t.NumRows = numRows(pager.FileLength, legacyRowSize)
+		return t, nil
+	case pager.FileLength < PageSize:
+		return nil, engine.NewError(engine.ExecuteCorrupt, fmt.Errorf("Truncated header page"))
+	}
+
+	if t.columns, err = schema.Decode(header); err != nil {
+		return nil, err
+	}
+	t.NumRows = numRows(pager.FileLength-PageSize, RowSize)
+
+	return t, nil
+}
+
+// numRows returns the number of rows in length bytes of pages of rows, of
+// which only the last one may be partial.
+func numRows(length, rowSize uint32) uint32 {
+	return length/PageSize*(PageSize/rowSize) + length%PageSize/rowSize
+}
+
+// Schema returns the columns of the table.
+func (t *Table) Schema() []engine.Column {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.columns
+}
+
+// AddColumn adds a column to the table without rewriting its rows.
+func (t *Table) AddColumn(column engine.Column) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.readOnly {
+		return engine.ErrReadOnly
+	}
+	if t.legacy {
+		return schema.ErrLegacy
+	}
+
+	header, err := t.Pager.GetPage(headerPageNum)
+	if err != nil {
+		return engine.NewError(engine.ExecutePageFetchError, err)
+	}
+	// the slice is replaced rather than appended to, as it's shared by Schema
+	columns := append(append([]engine.Column(nil), t.columns...), column)
+	if err := schema.Encode(header, columns); err != nil {
+		return err
+	}
+	t.columns = columns
+
+	return nil
+}
+
+// Count returns the number of rows.
+func (t *Table) Count(ctx context.Context) (int, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return int(t.NumRows), nil
 }
 
 func (t *Table) RowNums() uint32 {
@@ -41,52 +146,98 @@ func (t *Table) GetPager() engine.Pager {
 	return t.Pager
 }
 
-func (t *Table) Close() (engine.ExecutionStatus, error) {
+func (t *Table) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pager := t.Pager
+
+	// read only tables don't have changes to flush. The file is closed even
+	// if flushing fails, so its lock isn't kept.
+	var err error
+	if !t.readOnly && !t.legacy {
+		err = t.flush()
+	}
+
+	// close the DB file
+	if closeErr := pager.File.Close(); closeErr != nil && err == nil {
+		err = engine.NewError(engine.ExitFailure, fmt.Errorf("Error closing db file: %w", closeErr))
+	}
+
+	for i := 0; i < int(TableMaxPage); i++ {
+		if pager.Pages[i] != nil {
+			pager.Pages[i] = nil
+		}
+	}
+
+	return err
+}
+
+// Flush writes the changed pages into the DB file and syncs it.
+func (t *Table) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.readOnly || t.legacy {
+		return nil
+	}
+
+	return t.flush()
+}
+
+func (t *Table) flush() error {
 	pager := t.Pager
 
+	// the header page is always whole
+	if pager.Pages[headerPageNum] != nil {
+		if err := pager.Flush(int(headerPageNum), PageSize); err != nil {
+			return engine.NewError(engine.ExitFailure, err)
+		}
+		pager.Pages[headerPageNum] = nil
+	}
+
 	// flush pages and clean-up them
 	numFullPages := t.NumRows / RowsPerPage
-	for i := 0; i < int(numFullPages); i++ {
-		if pager.Pages[i] == nil {
+	for i := uint32(0); i < numFullPages; i++ {
+		pageNum := int(rowPageNum(i * RowsPerPage))
+		if pager.Pages[pageNum] == nil {
 			continue
 		}
-		if err := pager.Flush(i, PageSize); err != nil {
-			return engine.ExitFailure, err
+		if err := pager.Flush(pageNum, PageSize); err != nil {
+			return engine.NewError(engine.ExitFailure, err)
 		}
-		pager.Pages[i] = nil
+		pager.Pages[pageNum] = nil
 	}
 
 	// if we have partial page, we should write them to disk too.
 	numAdditionalRows := t.NumRows % RowsPerPage
-	if numAdditionalRows > 0 && pager.Pages[numFullPages] != nil { // partial page only can be occurred on the last page
-		if err := pager.Flush(int(numFullPages), numAdditionalRows*RowSize); err != nil {
-			return engine.ExitFailure, err
+	lastPageNum := int(rowPageNum(t.NumRows))
+	if numAdditionalRows > 0 && pager.Pages[lastPageNum] != nil { // partial page only can be occurred on the last page
+		if err := pager.Flush(lastPageNum, numAdditionalRows*RowSize); err != nil {
+			return engine.NewError(engine.ExitFailure, err)
 		}
-		pager.Pages[int(numFullPages)] = nil
+		pager.Pages[lastPageNum] = nil
 	}
 
-	// close the DB file
-	if err := pager.FileDescriptor.Close(); err != nil {
-		return engine.ExitFailure, fmt.Errorf("Error closing db file.")
+	if err := pager.File.Sync(); err != nil {
+		return engine.NewError(engine.ExitFailure, fmt.Errorf("Error syncing db file: %w", err))
 	}
 
-	for i := 0; i < int(TableMaxPage); i++ {
-		if pager.Pages[i] != nil {
-			pager.Pages[i] = nil
-		}
-	}
+	return nil
+}
 
-	return engine.ExecuteSuccess, nil
+// rowPageNum returns the page of the row rowNum.
+func rowPageNum(rowNum uint32) uint32 {
+	return headerPageNum + 1 + rowNum/RowsPerPage
 }
 
-func (t *Table) ExecuteMeta(command []byte) engine.ExecutionStatus {
-	return engine.MetaUnrecognizedCommand
+func (t *Table) ExecuteMeta(command []byte) error {
+	return engine.ErrUnrecognizedCommand
 }
 
 func cursorValue(cursor *cursor) ([]byte, uint32, error) {
 	rowNum := cursor.rowNum
-	pageNum := rowNum / RowsPerPage
-	page, err := cursor.table.GetPager().GetPage(pageNum)
+	page, err := cursor.table.GetPager().GetPage(rowPageNum(rowNum))
 	if err != nil {
 		return nil, 0, err
 	}
@@ -96,16 +247,27 @@ func cursorValue(cursor *cursor) ([]byte, uint32, error) {
 	return page, byteOffset, nil
 }
 
-func (t *Table) Insert(row *engine.Row) engine.ExecutionStatus {
-	if t.NumRows > TableMaxPage {
-		return engine.ExecuteTableFull
+func (t *Table) Insert(row *engine.Row) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.readOnly {
+		return engine.ErrReadOnly
+	}
+	if t.legacy {
+		return schema.ErrLegacy
+	}
+	if t.NumRows >= TableMaxRows {
+		return engine.ErrTableFull
+	}
+	if err := utils.CheckSize(row, RowSize); err != nil {
+		return err
 	}
 
 	cursor := tableEnd(t)
 	page, byteOffset, err := cursorValue(cursor)
 	if err != nil {
-		fmt.Println(err)
-		return engine.ExecutePageFetchError
+		return engine.NewError(engine.ExecutePageFetchError, err)
 	}
 	serializedRow := utils.Serialize(binary.LittleEndian, row)
 
@@ -113,25 +275,34 @@ func (t *Table) Insert(row *engine.Row) engine.ExecutionStatus {
 
 	t.NumRows++
 
-	return engine.ExecuteSuccess
+	return nil
 }
 
-func (t *Table) Select() ([]*engine.Row, engine.ExecutionStatus) {
+func (t *Table) Select(ctx context.Context) ([]*engine.Row, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.legacy {
+		return t.legacySelect(ctx)
+	}
+
 	var result []*engine.Row
 	cursor := tableStart(t)
 	for !cursor.endOfTable {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		page, byteOffset, err := cursorValue(cursor)
 		if err != nil {
-			fmt.Println(err)
-			return nil, engine.ExecutePageFetchError
+			return nil, engine.NewError(engine.ExecutePageFetchError, err)
 		}
-		row := utils.Deserialize(binary.LittleEndian, page[byteOffset:byteOffset+RowSize])
-		if row == nil {
-			return nil, engine.ExecuteRowNotFound
+		row, err := utils.Deserialize(binary.LittleEndian, page[byteOffset:byteOffset+RowSize])
+		if err != nil {
+			return nil, err
 		}
 		result = append(result, row)
 		cursor.Advance()
 	}
 
-	return result, engine.ExecuteSuccess
+	return result, nil
 }
@@ -0,0 +1,91 @@
+package arraylike
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/utils"
+)
+
+func TestTableConcurrentReadersAndWriters(t *testing.T) {
+	table, err := DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	numRows := 50
+	var wg sync.WaitGroup
+	for i := 1; i <= numRows; i++ {
+		wg.Add(2)
+		go func(id int) {
+			defer wg.Done()
+			row := engine.NewRow(uint32(id), engine.NewText(fmt.Sprintf("user%d", id)), engine.NewText(fmt.Sprintf("person%d@example.com", id)))
+			if err := table.Insert(row); err != nil {
+				t.Errorf("Insert() error = %v", err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := table.Select(context.Background()); err != nil {
+				t.Errorf("Select() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	rows, err := table.Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != numRows {
+		t.Errorf("Select() returned %d rows, want %d", len(rows), numRows)
+	}
+}
+
+func TestLegacyFile(t *testing.T) {
+	// rows of 514 bytes from the start of the file, without a header page
+	var data []byte
+	for i := uint32(1); i <= legacyRowsPerPage+1; i++ {
+		if i == legacyRowsPerPage+1 {
+			data = append(data, make([]byte, PageSize-uint32(len(data)))...)
+		}
+		slot := make([]byte, legacyRowSize)
+		copy(slot, utils.Serialize(binary.LittleEndian, engine.NewRow(i, engine.NewText("user"), engine.Null)))
+		data = append(data, slot...)
+	}
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := DbOpen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := table.Select(context.Background())
+	if err != nil || len(rows) != int(legacyRowsPerPage)+1 || rows[legacyRowsPerPage].Id != legacyRowsPerPage+1 {
+		t.Errorf("Select() = %v, %v, want %d rows", rows, err, legacyRowsPerPage+1)
+	}
+	if err := table.Insert(engine.NewRow(100, engine.Null, engine.Null)); !errors.Is(err, engine.ErrReadOnly) {
+		t.Errorf("Insert() error = %v, want %v", err, engine.ErrReadOnly)
+	}
+	if err := table.AddColumn(engine.Column{Name: "age"}); !errors.Is(err, engine.ErrReadOnly) {
+		t.Errorf("AddColumn() error = %v, want %v", err, engine.ErrReadOnly)
+	}
+	if err := table.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Errorf("Close() changed the legacy file")
+	}
+}
@@ -2,8 +2,12 @@ package btree
 
 import (
 	"fmt"
+
+	"github.com/meysampg/sqltut/engine"
 )
 
+var errNeedInternalNodeSearch = engine.NewError(engine.TODO, fmt.Errorf("Need to implement searching an internal node"))
+
 type cursor struct {
 	table      *Table
 	pageNum    uint32
@@ -31,6 +35,12 @@ func tableStart(table *Table) (*cursor, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkNode(Orderness, rootPage); err != nil {
+		return nil, err
+	}
+	if getNodeType(Orderness, rootPage) != NodeLeaf {
+		return nil, errNeedInternalNodeSearch
+	}
 	numCells := getLeafNodeNumCells(Orderness, rootPage)
 
 	return &cursor{
@@ -46,12 +56,15 @@ func tableFind(table *Table, key uint32) (*cursor, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkNode(Orderness, node); err != nil {
+		return nil, err
+	}
 
 	if getNodeType(Orderness, node) == NodeLeaf {
 		return leafNodeFind(table, table.rootPageNum, key)
 	}
 
-	return nil, fmt.Errorf("Need to implement searching an internal node")
+	return nil, errNeedInternalNodeSearch
 }
 
 func leafNodeFind(table *Table, pageNum uint32, key uint32) (*cursor, error) {
@@ -59,6 +72,9 @@ func leafNodeFind(table *Table, pageNum uint32, key uint32) (*cursor, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkNode(Orderness, node); err != nil {
+		return nil, err
+	}
 
 	cur := &cursor{
 		table:   table,
@@ -93,6 +109,9 @@ func cursorValue(cursor *cursor) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkLeafCell(Orderness, page, cursor.cellNum); err != nil {
+		return nil, err
+	}
 
 	return leafNodeValue(Orderness, page, cursor.cellNum), nil
 }
@@ -0,0 +1,47 @@
+package btree
+
+import (
+	"context"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/utils"
+)
+
+// Legacy files don't have a header page and their rows have slots of 514
+// bytes, the root is the page 0.
+const (
+	legacyRowSize  uint32 = 4 + 255 + 255
+	legacyCellSize        = LeafNodeKeySize + legacyRowSize
+	legacyMaxCells        = LeafNodeSpaceForCells / legacyCellSize
+)
+
+// legacySelect returns the rows of a legacy file. Only the root leaf is
+// read, as the versions which wrote them couldn't read other trees either.
+func (t *Table) legacySelect(ctx context.Context) ([]*engine.Row, error) {
+	root, err := t.pager.GetPage(t.rootPageNum)
+	if err != nil {
+		return nil, pageFetchError(err)
+	}
+	if getNodeType(Orderness, root) != NodeLeaf {
+		return nil, errNeedInternalNodeSearch
+	}
+	numCells := getLeafNodeNumCells(Orderness, root)
+	if numCells > legacyMaxCells {
+		return nil, corruptNode("Leaf node has %d cells, at most %d fit", numCells, legacyMaxCells)
+	}
+
+	var result []*engine.Row
+	for i := uint32(0); i < numCells; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		offset := LeafNodeHeaderSize + i*legacyCellSize + LeafNodeValueOffset
+		row, err := utils.Deserialize(Orderness, root[offset:offset+legacyRowSize])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
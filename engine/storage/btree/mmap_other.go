@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package btree
+
+import "github.com/meysampg/sqltut/engine"
+
+// mmap isn't supported on platforms without it, pages are read into buffers.
+func mmap(f engine.File, size int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package btree
+
+import (
+	"syscall"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// mmap maps size bytes of the file privately, so changes of pages in the
+// mapping stay in memory until they're flushed by the pager.
+func mmap(f engine.File, size int) ([]byte, error) {
+	fder, ok := f.(interface{ Fd() uintptr })
+	if !ok {
+		return nil, errMmapUnsupported
+	}
+
+	return syscall.Mmap(int(fder.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+}
+
+func munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
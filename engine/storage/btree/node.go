@@ -77,6 +77,7 @@ const (
 	InternalNodeChildOffset = InternalNodeKeyOffset + InternalNodeKeySize
 	InternalNodeChildSize   = 4
 	InternalNodeCellSize    = InternalNodeChildSize + InternalNodeKeySize
+	InternalNodeMaxCells    = (PageSize - InternalNodeHeaderSize) / InternalNodeCellSize
 )
 
 var Orderness binary.ByteOrder = binary.LittleEndian
@@ -97,8 +98,13 @@ func leafNodeCell(order binary.ByteOrder, node []byte, cellNum uint32) []byte {
 	return node[offsetOfLeafCell(cellNum):offsetOfLeafCell(cellNum+1)]
 }
 
-func getLeafNodeCell(order binary.ByteOrder, node []byte, cellNum uint32) (uint32, *engine.Row) {
-	return getLeafNodeKey(order, node, cellNum), getLeafNodeValue(order, node, cellNum)
+func getLeafNodeCell(order binary.ByteOrder, node []byte, cellNum uint32) (uint32, *engine.Row, error) {
+	row, err := getLeafNodeValue(order, node, cellNum)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return getLeafNodeKey(order, node, cellNum), row, nil
 }
 
 func setLeafNodeCell(order binary.ByteOrder, node []byte, cellNum uint32, key uint32, value *engine.Row) {
@@ -128,7 +134,11 @@ func leafNodeValue(order binary.ByteOrder, node []byte, cellNum uint32) []byte {
 	return value
 }
 
-func getLeafNodeValue(order binary.ByteOrder, node []byte, cellNum uint32) *engine.Row {
+func getLeafNodeValue(order binary.ByteOrder, node []byte, cellNum uint32) (*engine.Row, error) {
+	if err := checkLeafCell(order, node, cellNum); err != nil {
+		return nil, err
+	}
+
 	return utils.Deserialize(order, leafNodeValue(order, node, cellNum))
 }
 
@@ -164,25 +174,70 @@ func setIsNodeRoot(order binary.ByteOrder, node []byte, isRoot bool) {
 	copy(isNodeRoot(order, node), []byte{b2i[isRoot]})
 }
 
-func createNewRoot(table *Table, rightChildPageNum uint32) (engine.ExecutionStatus, error) {
+// checkNode validates the header of a node which is read from the DB file, so
+// the cells which it counts are inside of the page.
+func checkNode(order binary.ByteOrder, node []byte) error {
+	if uint32(len(node)) < PageSize {
+		return corruptNode("Node of %d bytes is smaller than a page", len(node))
+	}
+	if isRoot := isNodeRoot(order, node)[0]; isRoot > 1 {
+		return corruptNode("Invalid root flag %d", isRoot)
+	}
+
+	switch typ := getNodeType(order, node); typ {
+	case NodeLeaf:
+		if numCells := getLeafNodeNumCells(order, node); numCells > LeafNodeMaxCells {
+			return corruptNode("Leaf node has %d cells, at most %d fit", numCells, LeafNodeMaxCells)
+		}
+	case NodeInternal:
+		if numKeys := getInternalNodeNumKeys(order, node); numKeys > InternalNodeMaxCells {
+			return corruptNode("Internal node has %d keys, at most %d fit", numKeys, InternalNodeMaxCells)
+		}
+	default:
+		return corruptNode("Invalid node type %d", typ)
+	}
+
+	return nil
+}
+
+// checkLeafCell validates that node is a leaf which has the cell cellNum.
+func checkLeafCell(order binary.ByteOrder, node []byte, cellNum uint32) error {
+	if err := checkNode(order, node); err != nil {
+		return err
+	}
+	if getNodeType(order, node) != NodeLeaf {
+		return corruptNode("Tried to read cell %d of an internal node", cellNum)
+	}
+	if numCells := getLeafNodeNumCells(order, node); cellNum >= numCells {
+		return corruptNode("Tried to read cell %d of a leaf node of %d cells", cellNum, numCells)
+	}
+
+	return nil
+}
+
+func corruptNode(format string, args ...interface{}) error {
+	return engine.NewError(engine.ExecuteCorrupt, fmt.Errorf(format, args...))
+}
+
+func createNewRoot(table *Table, rightChildPageNum uint32) error {
 	root, err := table.pager.GetPage(table.rootPageNum)
 	if err != nil {
-		return engine.ExitFailure, err
+		return err
 	}
 
 	_, err = table.pager.GetPage(rightChildPageNum)
 	if err != nil {
-		return engine.ExitFailure, err
+		return err
 	}
 
 	leftChildPageNum, err := getUnusedPageNum(table.pager)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	leftChild, err := table.pager.GetPage(leftChildPageNum)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	copy(leftChild, root) // copy root to left child
@@ -191,15 +246,14 @@ func createNewRoot(table *Table, rightChildPageNum uint32) (engine.ExecutionStat
 	initializeInternalNode(Orderness, root)
 	setIsNodeRoot(Orderness, root, true)
 	setInternalNodeNumKeys(Orderness, root, 1)
-	status, err := setInternalNodeChildPage(Orderness, root, 0, leftChildPageNum)
-	if err != nil {
-		return status, err
+	if err := setInternalNodeChildPage(Orderness, root, 0, leftChildPageNum); err != nil {
+		return err
 	}
 	leftChildMaxKey := getNodeMaxKey(Orderness, leftChild)
 	setInternalNodeKey(Orderness, root, 0, leftChildMaxKey)
 	setInternalNodeRightChild(Orderness, root, rightChildPageNum)
 
-	return engine.ExecuteSuccess, nil
+	return nil
 }
 
 func initializeLeafNode(order binary.ByteOrder, node []byte) {
@@ -260,55 +314,55 @@ func setInternalNodeRightChild(order binary.ByteOrder, node []byte, pageNum uint
 	order.PutUint32(internalNodeRightChild(order, node), pageNum)
 }
 
-func internalNodeChild(order binary.ByteOrder, node []byte, childNum uint32) ([]byte, engine.ExecutionStatus, error) {
+func internalNodeChild(order binary.ByteOrder, node []byte, childNum uint32) ([]byte, error) {
 	numKeys := getInternalNodeNumKeys(order, node)
 	if childNum > numKeys {
-		return nil, engine.ExitFailure, fmt.Errorf("Tried to access child_num %d > num_keys %d\n", childNum, numKeys)
+		return nil, engine.NewError(engine.ExitFailure, fmt.Errorf("Tried to access child_num %d > num_keys %d", childNum, numKeys))
 	} else if childNum == numKeys {
-		return internalNodeRightChild(order, node), 0, nil
+		return internalNodeRightChild(order, node), nil
 	}
 
-	return internalNodeCell(order, node, childNum), 0, nil
+	return internalNodeCell(order, node, childNum), nil
 }
 
-func getInternalNodeChildKey(order binary.ByteOrder, node []byte, childNum uint32) (uint32, engine.ExecutionStatus, error) {
-	bytes, status, err := internalNodeChild(order, node, childNum)
-	if err != nil || status != 0 {
-		return 0, status, err
+func getInternalNodeChildKey(order binary.ByteOrder, node []byte, childNum uint32) (uint32, error) {
+	bytes, err := internalNodeChild(order, node, childNum)
+	if err != nil {
+		return 0, err
 	}
 
-	return order.Uint32(bytes[InternalNodeKeyOffset : InternalNodeKeyOffset+InternalNodeKeySize]), 0, nil
+	return order.Uint32(bytes[InternalNodeKeyOffset : InternalNodeKeyOffset+InternalNodeKeySize]), nil
 }
 
-func setInternalNodeChildKey(order binary.ByteOrder, node []byte, childNum uint32, key uint32) (engine.ExecutionStatus, error) {
-	bytes, status, err := internalNodeChild(order, node, childNum)
-	if err != nil || status != 0 {
-		return status, err
+func setInternalNodeChildKey(order binary.ByteOrder, node []byte, childNum uint32, key uint32) error {
+	bytes, err := internalNodeChild(order, node, childNum)
+	if err != nil {
+		return err
 	}
 
 	order.PutUint32(bytes[InternalNodeKeyOffset:InternalNodeKeyOffset+InternalNodeKeySize], key)
 
-	return 0, nil
+	return nil
 }
 
-func getInternalNodeChildPage(order binary.ByteOrder, node []byte, childNum uint32) (uint32, engine.ExecutionStatus, error) {
-	bytes, status, err := internalNodeChild(order, node, childNum)
-	if err != nil || status != 0 {
-		return 0, status, err
+func getInternalNodeChildPage(order binary.ByteOrder, node []byte, childNum uint32) (uint32, error) {
+	bytes, err := internalNodeChild(order, node, childNum)
+	if err != nil {
+		return 0, err
 	}
 
-	return order.Uint32(bytes[InternalNodeChildOffset : InternalNodeChildOffset+InternalNodeChildSize]), 0, nil
+	return order.Uint32(bytes[InternalNodeChildOffset : InternalNodeChildOffset+InternalNodeChildSize]), nil
 }
 
-func setInternalNodeChildPage(order binary.ByteOrder, node []byte, childNum uint32, pageNum uint32) (engine.ExecutionStatus, error) {
-	bytes, status, err := internalNodeChild(order, node, childNum)
-	if err != nil || status != 0 {
-		return status, err
+func setInternalNodeChildPage(order binary.ByteOrder, node []byte, childNum uint32, pageNum uint32) error {
+	bytes, err := internalNodeChild(order, node, childNum)
+	if err != nil {
+		return err
 	}
 
 	order.PutUint32(bytes[InternalNodeChildOffset:InternalNodeChildOffset+InternalNodeChildSize], pageNum)
 
-	return 0, nil
+	return nil
 }
 
 func getNodeMaxKey(order binary.ByteOrder, node []byte) uint32 {
@@ -322,10 +376,10 @@ func getNodeMaxKey(order binary.ByteOrder, node []byte) uint32 {
 	}
 }
 
-func leafNodeInsert(c *cursor, key uint32, value *engine.Row) (engine.ExecutionStatus, error) {
+func leafNodeInsert(c *cursor, key uint32, value *engine.Row) error {
 	node, err := c.table.pager.GetPage(c.pageNum)
 	if err != nil {
-		return engine.ExitFailure, err
+		return err
 	}
 
 	numCells := getLeafNodeNumCells(Orderness, node)
@@ -335,7 +389,7 @@ func leafNodeInsert(c *cursor, key uint32, value *engine.Row) (engine.ExecutionS
 
 	if c.cellNum < numCells {
 		if getLeafNodeKey(Orderness, node, c.cellNum) == key {
-			return engine.ExecuteDuplicateKey, nil
+			return engine.ErrDuplicateKey
 		}
 
 		var i uint32
@@ -347,23 +401,23 @@ func leafNodeInsert(c *cursor, key uint32, value *engine.Row) (engine.ExecutionS
 	setLeafNodeNumCells(Orderness, node, getLeafNodeNumCells(Orderness, node)+1)
 	setLeafNodeCell(Orderness, node, c.cellNum, key, value)
 
-	return engine.ExecuteSuccess, nil
+	return nil
 }
 
-func leafNodeSplitAndInsert(c *cursor, key uint32, value *engine.Row) (engine.ExecutionStatus, error) {
+func leafNodeSplitAndInsert(c *cursor, key uint32, value *engine.Row) error {
 	oldPage, err := c.table.pager.GetPage(c.pageNum)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	newPageNum, err := getUnusedPageNum(c.table.pager)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	newPage, err := c.table.pager.GetPage(newPageNum)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	initializeLeafNode(Orderness, newPage)
@@ -397,7 +451,7 @@ func leafNodeSplitAndInsert(c *cursor, key uint32, value *engine.Row) (engine.Ex
 		return createNewRoot(c.table, newPageNum)
 	}
 
-	return engine.ExitFailure, fmt.Errorf("Need to implement updating parent after split")
+	return engine.NewError(engine.TODO, fmt.Errorf("Need to implement updating parent after split"))
 }
 
 func getUnusedPageNum(p *Pager) (uint32, error) {
@@ -0,0 +1,111 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/schema"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
+)
+
+// openPages opens a table of a DB file which has a header page and then
+// the given pages, the root first.
+func openPages(t testing.TB, data []byte) *Table {
+	t.Helper()
+	header := make([]byte, PageSize)
+	if err := schema.Encode(header, engine.Schema); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := vfs.NewMemory()
+	f, err := fs.Open("test.db", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt(append(header, data...), 0); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	table, err := DbOpenWithOptions("test.db", engine.Options{VFS: fs})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return table
+}
+
+// leafPage returns a root leaf of the given number of cells.
+func leafPage(numCells uint32, rows ...*engine.Row) []byte {
+	page := make([]byte, PageSize)
+	initializeLeafNode(Orderness, page)
+	setIsNodeRoot(Orderness, page, true)
+	for i, row := range rows {
+		setLeafNodeCell(Orderness, page, uint32(i), row.Id, row)
+	}
+	setLeafNodeNumCells(Orderness, page, numCells)
+
+	return page
+}
+
+func TestCorruptNode(t *testing.T) {
+	row := engine.NewRow(1, engine.NewText("user1"), engine.NewText("person1@example.com"))
+	badType := leafPage(1, row)
+	setNodeType(Orderness, badType, 7)
+	badRow := leafPage(1, row)
+	leafNodeValue(Orderness, badRow, 0)[0] = 0xff
+
+	tests := []struct {
+		name string
+		page []byte
+	}{
+		{name: "too many cells", page: leafPage(LeafNodeMaxCells+1, row)},
+		{name: "max uint32 cells", page: leafPage(1<<32-1, row)},
+		{name: "invalid node type", page: badType},
+		{name: "invalid row", page: badRow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := openPages(t, tt.page)
+			defer table.Close()
+
+			if rows, err := table.Select(context.Background()); !errors.Is(err, engine.ErrCorrupt) {
+				t.Errorf("Select() = %v, %v, want %v", rows, err, engine.ErrCorrupt)
+			}
+		})
+	}
+
+	page := make([]byte, PageSize)
+	initializeInternalNode(Orderness, page)
+	setIsNodeRoot(Orderness, page, true)
+	setInternalNodeRightChild(Orderness, page, TableMaxPage)
+	table := openPages(t, page)
+	defer table.Close()
+	if err := table.ExecuteMeta([]byte(".btree")); !errors.Is(err, engine.ErrCorrupt) {
+		t.Errorf("ExecuteMeta(.btree) with a child out of bounds error = %v, want %v", err, engine.ErrCorrupt)
+	}
+}
+
+func FuzzNode(f *testing.F) {
+	row := engine.NewRow(1, engine.NewText("user1"), engine.NewText("person1@example.com"))
+	f.Add(leafPage(1, row))
+	f.Add(leafPage(2, row, engine.NewRow(2, engine.Null, engine.NewInteger(-1))))
+	f.Add(leafPage(LeafNodeMaxCells+1, row))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// files are a whole number of pages
+		page := make([]byte, PageSize)
+		copy(page, data)
+
+		table := openPages(t, page)
+		defer table.Close()
+
+		if _, err := table.Select(context.Background()); err != nil && engine.StatusOf(err) == engine.ExitFailure {
+			t.Errorf("Select() error = %v, want an engine.Error", err)
+		}
+		if err := table.Insert(row); err != nil && engine.StatusOf(err) == engine.ExitFailure {
+			t.Errorf("Insert() error = %v, want an engine.Error", err)
+		}
+	})
+}
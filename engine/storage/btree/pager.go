@@ -1,47 +1,77 @@
 package btree
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"sync"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
 )
 
+// Pager caches the pages of the DB file. It's safe for concurrent use, but the
+// content of pages is guarded by the Table.
 type Pager struct {
-	fileDescriptor *os.File
-	fileLength     uint32
-	pages          [][]byte
-	numPages       uint32
+	mu         sync.Mutex
+	file       engine.File
+	fileLength uint32
+	pages      [][]byte
+	numPages   uint32
+
+	// mmap serves the pages of the file from a private mapping instead of
+	// reading them into buffers. Changed pages are written by Flush like the
+	// others. The last mapping has the whole file, older ones are kept until
+	// close since cached pages may be slices of them.
+	mmap     bool
+	mappings [][]byte
 }
 
-func NewPager(filename string) (*Pager, error) {
-	fd, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0666)
+var errMmapUnsupported = errors.New("Memory mapping is not supported")
+
+// NewPager opens the DB file by the VFS of opts and locks it, exclusively
+// unless it's opened as read only.
+func NewPager(filename string, opts engine.Options) (*Pager, error) {
+	file, err := vfs.Open(filename, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	fileLength, err := fd.Seek(0, io.SeekEnd)
+	fileLength, err := file.Size()
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
 
 	if uint32(fileLength)%PageSize != 0 {
+		file.Close()
 		return nil, fmt.Errorf("Db file is not a whole number of pages. Corrupt file.")
 	}
 
 	return &Pager{
-		fileDescriptor: fd,
-		fileLength:     uint32(fileLength),
-		pages:          make([][]byte, TableMaxPage, TableMaxPage),
-		numPages:       uint32(fileLength) / PageSize,
+		file:       file,
+		fileLength: uint32(fileLength),
+		pages:      make([][]byte, TableMaxPage, TableMaxPage),
+		numPages:   uint32(fileLength) / PageSize,
+		mmap:       opts.MMap,
 	}, nil
 }
 
 func (p *Pager) GetNumPages() uint32 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	return p.numPages
 }
 
 func (p *Pager) GetPage(pageNum uint32) ([]byte, error) {
-	if pageNum >= p.GetNumPages() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pageNum >= TableMaxPage {
+		return nil, engine.NewError(engine.ExecuteCorrupt, fmt.Errorf("Tried to fetch page number out of bounds. %d >= %d", pageNum, TableMaxPage))
+	}
+	if pageNum >= p.numPages {
 		p.numPages = pageNum + 1
 	}
 
@@ -54,15 +84,30 @@ func (p *Pager) GetPage(pageNum uint32) ([]byte, error) {
 			numPages++
 		}
 
+		if pageNum < numPages && p.mmap {
+			mapped, err := p.mappedPage(pageNum)
+			switch {
+			case err == nil:
+				p.pages[pageNum] = mapped
+				return mapped, nil
+			case errors.Is(err, errMmapUnsupported):
+				// e.g. the file isn't a file of the OS, use buffers instead
+				p.mmap = false
+			default:
+				return nil, fmt.Errorf("Error mapping page %d: %w", pageNum, err)
+			}
+		}
+
 		// if we already have page on disk, will try to load it. Otherwise, we don't have this page and can skip this step.
 		if pageNum < numPages {
-			_, err := p.fileDescriptor.Seek(int64(pageNum*PageSize), io.SeekStart)
-			if err != nil {
-				return nil, err
+			offset := int64(pageNum) * int64(PageSize)
+			n, err := vfs.ReadAt(p.file, page, offset)
+			// pages are whole, a partial one means the file is truncated
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
 			}
-			n, err := p.fileDescriptor.Read(page)
-			if n < 0 || err != nil {
-				return nil, fmt.Errorf("Error reading file: %d", n) // f*ck the errno :))
+			if err != nil {
+				return nil, fmt.Errorf("Error reading page %d at offset %d, read %d of %d bytes: %w", pageNum, offset, n, PageSize, err)
 			}
 		}
 
@@ -73,17 +118,57 @@ func (p *Pager) GetPage(pageNum uint32) ([]byte, error) {
 }
 
 func (p *Pager) Flush(pageNum int, size uint32) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if p.pages[pageNum] == nil {
 		return fmt.Errorf("Tried to flush null page")
 	}
 
-	if ret, err := p.fileDescriptor.Seek(int64(pageNum*int(PageSize)), io.SeekStart); err != nil || ret < 0 {
-		return fmt.Errorf("Error seeking: %d", ret)
+	offset := int64(pageNum) * int64(PageSize)
+	if n, err := vfs.WriteAt(p.file, p.pages[pageNum][:PageSize], offset); err != nil {
+		return fmt.Errorf("Error writing page %d at offset %d, wrote %d of %d bytes: %w", pageNum, offset, n, PageSize, err)
 	}
-
-	if n, err := p.fileDescriptor.Write(p.pages[pageNum][:PageSize]); n < 0 || err != nil {
-		return fmt.Errorf("Error writing: %d", n)
+	if end := uint32(offset) + PageSize; end > p.fileLength {
+		p.fileLength = end
 	}
 
 	return nil
 }
+
+// mappedPage returns the page of the mapping, which is remapped if the file
+// has grown past it.
+func (p *Pager) mappedPage(pageNum uint32) ([]byte, error) {
+	end := int(pageNum+1) * int(PageSize)
+	if len(p.mappings) == 0 || len(p.mappings[len(p.mappings)-1]) < end {
+		mapping, err := mmap(p.file, int(p.fileLength))
+		if err != nil {
+			return nil, err
+		}
+		p.mappings = append(p.mappings, mapping)
+	}
+
+	mapping := p.mappings[len(p.mappings)-1]
+
+	return mapping[end-int(PageSize) : end : end], nil
+}
+
+// close unmaps the file and closes it. Pages must not be used afterwards.
+func (p *Pager) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	for _, mapping := range p.mappings {
+		if unmapErr := munmap(mapping); unmapErr != nil && err == nil {
+			err = unmapErr
+		}
+	}
+	p.mappings = nil
+
+	if closeErr := p.file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,189 @@
+package btree
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
+)
+
+func TestPagerMmap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	insert := func(table *Table, ids ...uint32) {
+		t.Helper()
+		for _, id := range ids {
+			if err := table.Insert(engine.NewRow(id, engine.NewText("user"), engine.NewText("person@example.com"))); err != nil {
+				t.Fatalf("Insert(%d) error = %v", id, err)
+			}
+		}
+	}
+	selectIds := func(table *Table) []uint32 {
+		t.Helper()
+		rows, err := table.Select(context.Background())
+		if err != nil {
+			t.Fatalf("Select() error = %v", err)
+		}
+		var ids []uint32
+		for _, row := range rows {
+			ids = append(ids, row.Id)
+		}
+		return ids
+	}
+
+	table, err := DbOpenWithOptions(path, engine.Options{MMap: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert(table, 1, 2, 3)
+	if err := table.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// pages on disk are mapped, and can be changed and flushed
+	table, err = DbOpenWithOptions(path, engine.Options{MMap: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := selectIds(table); len(ids) != 3 {
+		t.Errorf("Select() ids = %v, want [1 2 3]", ids)
+	}
+	if len(table.pager.mappings) != 1 {
+		t.Errorf("len(mappings) = %d, want 1", len(table.pager.mappings))
+	}
+	insert(table, 4)
+	if err := table.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	table, err = DbOpen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if ids := selectIds(table); len(ids) != 4 || ids[3] != 4 {
+		t.Errorf("Select() ids after reopening = %v, want [1 2 3 4]", ids)
+	}
+}
+
+func TestPagerRemap(t *testing.T) {
+	pager, err := NewPager(filepath.Join(t.TempDir(), "test.db"), engine.Options{MMap: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pager.close()
+
+	page, err := pager.GetPage(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page[0] = 1
+	if err := pager.Flush(0, PageSize); err != nil {
+		t.Fatal(err)
+	}
+	// the first page is mapped once it's on disk
+	pager.pages[0] = nil
+	page, err = pager.GetPage(0)
+	if !pager.mmap {
+		t.Skip(errMmapUnsupported)
+	}
+	if err != nil || page[0] != 1 || len(pager.mappings) != 1 {
+		t.Fatalf("GetPage(0) = %d, %v with %d mappings, want 1 with 1 mapping", page[0], err, len(pager.mappings))
+	}
+
+	// the file grows past the mapping by a page which isn't cached
+	page, err = pager.GetPage(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page[0] = 3
+	if err := pager.Flush(2, PageSize); err != nil {
+		t.Fatal(err)
+	}
+	pager.pages[2] = nil
+	page, err = pager.GetPage(2)
+	if err != nil || page[0] != 3 {
+		t.Fatalf("GetPage(2) = %d, %v, want 3", page[0], err)
+	}
+	if len(pager.mappings) != 2 || len(pager.mappings[1]) != int(3*PageSize) {
+		t.Errorf("mappings after growing = %d, want 2 of which the last has 3 pages", len(pager.mappings))
+	}
+	// pages of the older mapping are still valid
+	if page, err := pager.GetPage(0); err != nil || page[0] != 1 {
+		t.Errorf("GetPage(0) after remapping = %d, %v, want 1", page[0], err)
+	}
+}
+
+func TestPagerMmapUnsupported(t *testing.T) {
+	fs := vfs.NewMemory()
+	table, err := DbOpenWithOptions("test.db", engine.Options{VFS: fs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Insert(engine.NewRow(1, engine.NewText("user1"), engine.NewText("person1@example.com"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// files which can't be mapped are read into buffers
+	table, err = DbOpenWithOptions("test.db", engine.Options{VFS: fs, MMap: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if rows, err := table.Select(context.Background()); err != nil || len(rows) != 1 {
+		t.Errorf("Select() = %d rows, %v, want 1 row", len(rows), err)
+	}
+	if table.pager.mmap || len(table.pager.mappings) != 0 {
+		t.Errorf("mmap, len(mappings) = %v, %d, want false, 0", table.pager.mmap, len(table.pager.mappings))
+	}
+}
+
+// BenchmarkGetPage reads every page of a file through a new pager, either by
+// copying them into buffers or from a mapping.
+func BenchmarkGetPage(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "test.db")
+	pager, err := NewPager(path, engine.Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < int(TableMaxPage); i++ {
+		page, err := pager.GetPage(uint32(i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		page[0] = byte(i)
+		if err := pager.Flush(i, PageSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+	pager.close()
+
+	for _, bm := range []struct {
+		name string
+		mmap bool
+	}{
+		{"buffered", false},
+		{"mmap", true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(TableMaxPage * PageSize))
+			for n := 0; n < b.N; n++ {
+				pager, err := NewPager(path, engine.Options{ReadOnly: true, MMap: bm.mmap})
+				if err != nil {
+					b.Fatal(err)
+				}
+				for i := uint32(0); i < TableMaxPage; i++ {
+					page, err := pager.GetPage(i)
+					if err != nil || page[0] != byte(i) {
+						b.Fatalf("GetPage(%d) = %d, %v, want %d", i, page[0], err, i)
+					}
+				}
+				pager.close()
+			}
+		})
+	}
+}
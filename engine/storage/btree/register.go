@@ -0,0 +1,21 @@
+package btree
+
+import "github.com/meysampg/sqltut/engine"
+
+func init() {
+	engine.Register(engine.EngineInfo{
+		Name:        "btree",
+		Description: "B+tree of rows keyed by id",
+		Capabilities: engine.Capabilities{
+			Ranges:       true,
+			MetaCommands: []string{".btree", ".constants"},
+		},
+		Open: func(path string, opts engine.Options) (engine.Storage, error) {
+			t, err := DbOpenWithOptions(path, opts)
+			if err != nil {
+				return nil, err
+			}
+			return t, nil
+		},
+	})
+}
@@ -1,52 +1,179 @@
 package btree
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/schema"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
 	"github.com/meysampg/sqltut/engine/utils"
 )
 
 const (
 	PageSize     uint32 = 4096
 	TableMaxPage uint32 = 100
-	RowSize      uint32 = 4 + 255 + 255 // for the time being we assume string as a VARCHAR[255]
+	RowSize      uint32 = utils.RowSize
 )
 
+// headerPageNum is the page of the schema, the tree starts after it.
+const headerPageNum uint32 = 0
+
+// Table is safe for concurrent use. Statements which read the tree run
+// concurrently and writes are exclusive.
 type Table struct {
+	mu          sync.RWMutex
 	rootPageNum uint32
 	pager       *Pager
+	readOnly    bool
+	// legacy is set for DB files which were written before the header page,
+	// they are only read.
+	legacy  bool
+	columns []engine.Column
+	// fs is the VFS of the DB file, backups are written by it too.
+	fs engine.VFS
 }
 
 func DbOpen(filename string) (*Table, error) {
-	pager, err := NewPager(filename)
+	return DbOpenWithOptions(filename, engine.Options{})
+}
+
+func DbOpenWithOptions(filename string, opts engine.Options) (*Table, error) {
+	pager, err := NewPager(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := openTable(pager, opts)
 	if err != nil {
+		pager.close()
 		return nil, err
 	}
 
+	return t, nil
+}
+
+// openTable reads the header page of the DB file, or initializes the header
+// page and the root of a new one.
+func openTable(pager *Pager, opts engine.Options) (*Table, error) {
 	t := &Table{
-		rootPageNum: 0,
+		rootPageNum: headerPageNum + 1,
 		pager:       pager,
+		readOnly:    opts.ReadOnly,
+		columns:     engine.Schema,
+		fs:          vfs.FS(opts),
 	}
 
-	if pager.numPages == 0 {
-		rootNode, err := pager.GetPage(0)
-		if err != nil {
+	isNew := pager.numPages == 0
+	header, err := pager.GetPage(headerPageNum)
+	if err != nil {
+		return nil, pageFetchError(err)
+	}
+	if !isNew && !schema.IsHeader(header) {
+		t.legacy = true
+		t.rootPageNum = 0
+		return t, nil
+	} else if !isNew {
+		if t.columns, err = schema.Decode(header); err != nil {
 			return nil, err
 		}
+		return t, nil
+	}
 
-		initializeLeafNode(Orderness, rootNode)
-		setIsNodeRoot(Orderness, rootNode, true)
+	if err := schema.Encode(header, t.columns); err != nil {
+		return nil, err
 	}
+	rootNode, err := pager.GetPage(t.rootPageNum)
+	if err != nil {
+		return nil, pageFetchError(err)
+	}
+	initializeLeafNode(Orderness, rootNode)
+	setIsNodeRoot(Orderness, rootNode, true)
 
 	return t, nil
 }
 
+// Schema returns the columns of the table.
+func (t *Table) Schema() []engine.Column {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.columns
+}
+
+// AddColumn adds a column to the table without rewriting its rows.
+func (t *Table) AddColumn(column engine.Column) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.readOnly {
+		return engine.ErrReadOnly
+	}
+	if t.legacy {
+		return schema.ErrLegacy
+	}
+
+	header, err := t.pager.GetPage(headerPageNum)
+	if err != nil {
+		return pageFetchError(err)
+	}
+	// the slice is replaced rather than appended to, as it's shared by Schema
+	columns := append(append([]engine.Column(nil), t.columns...), column)
+	if err := schema.Encode(header, columns); err != nil {
+		return err
+	}
+	t.columns = columns
+
+	return nil
+}
+
 func (t *Table) GetPager() engine.Pager {
 	return t.pager
 }
 
-func (t *Table) Close() (engine.ExecutionStatus, error) {
+func (t *Table) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pager := t.pager
+	numPages := int(pager.GetNumPages())
+
+	// read only tables don't have changes to flush. The file is closed even
+	// if flushing fails, so its lock isn't kept.
+	var err error
+	if !t.readOnly && !t.legacy {
+		err = t.flush()
+	}
+
+	// close the DB file
+	if closeErr := pager.close(); closeErr != nil && err == nil {
+		err = engine.NewError(engine.ExitFailure, fmt.Errorf("Error closing db file: %w", closeErr))
+	}
+
+	for i := 0; i < numPages; i++ {
+		if pager.pages[i] != nil {
+			pager.pages[i] = nil
+		}
+	}
+
+	return err
+}
+
+// Flush writes the changed pages into the DB file and syncs it.
+func (t *Table) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.readOnly || t.legacy {
+		return nil
+	}
+
+	return t.flush()
+}
+
+func (t *Table) flush() error {
 	pager := t.pager
 
 	// flush pages and clean-up them
@@ -56,84 +183,129 @@ func (t *Table) Close() (engine.ExecutionStatus, error) {
 			continue
 		}
 		if err := pager.Flush(i, PageSize); err != nil {
-			return engine.ExitFailure, err
+			return engine.NewError(engine.ExitFailure, err)
 		}
 		pager.pages[i] = nil
 	}
 
-	// close the DB file
-	if err := pager.fileDescriptor.Close(); err != nil {
-		return engine.ExitFailure, fmt.Errorf("Error closing db file.")
-	}
-
-	for i := 0; i < numPages; i++ {
-		if pager.pages[i] != nil {
-			pager.pages[i] = nil
-		}
+	if err := pager.file.Sync(); err != nil {
+		return engine.NewError(engine.ExitFailure, fmt.Errorf("Error syncing db file: %w", err))
 	}
 
-	return engine.ExecuteSuccess, nil
+	return nil
 }
 
-func (t *Table) Insert(row *engine.Row) engine.ExecutionStatus {
-	cursor, err := tableFind(t, row.Id)
-	if err != nil {
-		if err.Error() == "Need to implement searching an internal node" {
-			return engine.TODO
-		}
+func (t *Table) Insert(row *engine.Row) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-		return engine.ExecutePageFetchError
+	if t.readOnly {
+		return engine.ErrReadOnly
+	}
+	if t.legacy {
+		return schema.ErrLegacy
+	}
+	if err := utils.CheckSize(row, LeafNodeValueSize); err != nil {
+		return err
 	}
 
-	status, err := leafNodeInsert(cursor, row.Id, row)
+	cursor, err := tableFind(t, row.Id)
 	if err != nil {
-		fmt.Println(err)
+		return pageFetchError(err)
 	}
 
-	return status
+	return pageFetchError(leafNodeInsert(cursor, row.Id, row))
 }
 
-func (t *Table) Select() ([]*engine.Row, engine.ExecutionStatus) {
+// pageFetchError marks the errors of the pager, which are not classified by
+// an engine.Error yet, as page fetch errors.
+func pageFetchError(err error) error {
+	var e *engine.Error
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+
+	return engine.NewError(engine.ExecutePageFetchError, err)
+}
+
+func (t *Table) Select(ctx context.Context) ([]*engine.Row, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.legacy {
+		return t.legacySelect(ctx)
+	}
+
 	var result []*engine.Row
 	cursor, err := tableStart(t)
 	if err != nil {
-		return nil, engine.ExecutePageFetchError
+		return nil, pageFetchError(err)
 	}
 	for !cursor.endOfTable {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		page, err := cursorValue(cursor)
 		if err != nil {
-			fmt.Println(err)
-			return nil, engine.ExecutePageFetchError
+			return nil, pageFetchError(err)
 		}
-		row := utils.Deserialize(Orderness, page)
-		if row == nil {
-			return nil, engine.ExecuteRowNotFound
+		row, err := utils.Deserialize(Orderness, page)
+		if err != nil {
+			return nil, err
 		}
 		result = append(result, row)
 		err = cursor.Advance()
 		if err != nil {
-			fmt.Println(err)
-			return nil, engine.ExecutePageFetchError
+			return nil, pageFetchError(err)
 		}
 	}
 
-	return result, engine.ExecuteSuccess
+	return result, nil
 }
 
-func (t *Table) ExecuteMeta(command []byte) engine.ExecutionStatus {
+// Count returns the number of rows, which are the cells of the root leaf.
+func (t *Table) Count(ctx context.Context) (int, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.legacy {
+		rows, err := t.legacySelect(ctx)
+		return len(rows), err
+	}
+
+	root, err := t.pager.GetPage(t.rootPageNum)
+	if err != nil {
+		return 0, pageFetchError(err)
+	}
+	if err := checkNode(Orderness, root); err != nil {
+		return 0, err
+	}
+	if getNodeType(Orderness, root) != NodeLeaf {
+		return 0, errNeedInternalNodeSearch
+	}
+
+	return int(getLeafNodeNumCells(Orderness, root)), nil
+}
+
+func (t *Table) ExecuteMeta(command []byte) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	if engine.Equal(command, ".constants") {
 		fmt.Println("Constants:")
 		printConstants()
 
-		return engine.MetaCommandSuccess
+		return nil
 	} else if engine.Equal(command, ".btree") {
+		if t.legacy {
+			return schema.ErrLegacy
+		}
 		fmt.Println("Tree:")
-		printTree(t.pager, 0, 0)
 
-		return engine.MetaCommandSuccess
+		return printTree(t.pager, t.rootPageNum, 0, map[uint32]bool{})
 	}
 
-	return engine.MetaUnrecognizedCommand
+	return engine.ErrUnrecognizedCommand
 }
 
 func printConstants() {
@@ -151,8 +323,21 @@ func indent(level uint32) {
 	}
 }
 
-func printTree(pager *Pager, pageNum uint32, indentationLevel uint32) {
-	node, _ := pager.GetPage(pageNum)
+// printTree prints the subtree of pageNum. visited has the pages which are
+// printed already, a corrupt file could make the tree a cycle.
+func printTree(pager *Pager, pageNum uint32, indentationLevel uint32, visited map[uint32]bool) error {
+	if visited[pageNum] {
+		return corruptNode("Page %d is a child of more than one node", pageNum)
+	}
+	visited[pageNum] = true
+
+	node, err := pager.GetPage(pageNum)
+	if err != nil {
+		return pageFetchError(err)
+	}
+	if err := checkNode(Orderness, node); err != nil {
+		return err
+	}
 	var numKeys, child uint32
 
 	switch getNodeType(Orderness, node) {
@@ -169,13 +354,17 @@ func printTree(pager *Pager, pageNum uint32, indentationLevel uint32) {
 		indent(indentationLevel)
 		fmt.Printf("- internal (size %d)\n", numKeys)
 		for i := uint32(0); i < numKeys; i++ {
-			child, _, _ = getInternalNodeChildPage(Orderness, node, i)
-			printTree(pager, child, indentationLevel+1)
+			child, _ = getInternalNodeChildPage(Orderness, node, i)
+			if err := printTree(pager, child, indentationLevel+1, visited); err != nil {
+				return err
+			}
 
 			indent(indentationLevel + 1)
 			fmt.Printf("- key %d\n", getInternalNodeKey(Orderness, node, i))
 		}
 		child = getInternalNodeRightChild(Orderness, node)
-		printTree(pager, child, indentationLevel+1)
+		return printTree(pager, child, indentationLevel+1, visited)
 	}
+
+	return nil
 }
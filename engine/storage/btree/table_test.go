@@ -0,0 +1,135 @@
+package btree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
+	"github.com/meysampg/sqltut/engine/utils"
+)
+
+func TestTableConcurrentReadersAndWriters(t *testing.T) {
+	table, err := DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	// keep the root leaf below LeafNodeMaxCells, so it's never split
+	numRows := int(LeafNodeMaxCells) - 1
+	var wg sync.WaitGroup
+	var duplicates sync.Map
+	for i := 1; i <= numRows; i++ {
+		for j := 0; j < 2; j++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				row := engine.NewRow(uint32(id), engine.NewText(fmt.Sprintf("user%d", id)), engine.NewText(fmt.Sprintf("person%d@example.com", id)))
+				if err := table.Insert(row); errors.Is(err, engine.ErrDuplicateKey) {
+					if _, loaded := duplicates.LoadOrStore(id, true); loaded {
+						t.Errorf("Insert() of id %d failed twice", id)
+					}
+				} else if err != nil {
+					t.Errorf("Insert() error = %v", err)
+				}
+			}(i)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rows, err := table.Select(context.Background())
+			if err != nil {
+				t.Errorf("Select() error = %v", err)
+			}
+			for k := 1; k < len(rows); k++ {
+				if rows[k-1].Id >= rows[k].Id {
+					t.Errorf("Select() rows are not sorted: %v", rows)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	rows, err := table.Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != numRows {
+		t.Errorf("Select() returned %d rows, want %d", len(rows), numRows)
+	}
+}
+
+func TestLegacyFile(t *testing.T) {
+	// a root leaf of cells of 514 bytes rows in the page 0, without a header page
+	page := make([]byte, PageSize)
+	initializeLeafNode(Orderness, page)
+	setIsNodeRoot(Orderness, page, true)
+	setLeafNodeNumCells(Orderness, page, 2)
+	for i := uint32(0); i < 2; i++ {
+		offset := LeafNodeHeaderSize + i*legacyCellSize
+		Orderness.PutUint32(page[offset:], i+1)
+		copy(page[offset+LeafNodeValueOffset:], utils.Serialize(Orderness, engine.NewRow(i+1, engine.NewText("user"), engine.Null)))
+	}
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(path, page, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := DbOpen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := table.Select(context.Background())
+	if err != nil || len(rows) != 2 || rows[1].Id != 2 || rows[1].Fields[0].Text() != "user" {
+		t.Errorf("Select() = %v, %v, want 2 rows", rows, err)
+	}
+	if err := table.Insert(engine.NewRow(3, engine.Null, engine.Null)); !errors.Is(err, engine.ErrReadOnly) {
+		t.Errorf("Insert() error = %v, want %v", err, engine.ErrReadOnly)
+	}
+	if err := table.AddColumn(engine.Column{Name: "age"}); !errors.Is(err, engine.ErrReadOnly) {
+		t.Errorf("AddColumn() error = %v, want %v", err, engine.ErrReadOnly)
+	}
+	if err := table.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, page) {
+		t.Errorf("Close() changed the legacy file")
+	}
+}
+
+func TestOpenPartialPage(t *testing.T) {
+	fs := vfs.NewMemory()
+	f, err := fs.Open("test.db", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(make([]byte, 100), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := engine.Options{VFS: fs}
+	if table, err := DbOpenWithOptions("test.db", opts); err == nil {
+		table.Close()
+		t.Fatalf("DbOpen() of a partial page error = nil")
+	}
+
+	// the failed open doesn't keep the file locked
+	if err := f.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	table, err := DbOpenWithOptions("test.db", opts)
+	if err != nil {
+		t.Fatalf("DbOpen() after a failed open error = %v", err)
+	}
+	table.Close()
+}
@@ -0,0 +1,46 @@
+// Package lock guards DB files against being opened by several processes, by
+// advisory locks on the file.
+//
+// The locks are flock(2) locks of Linux, macOS and the BSDs. Other platforms,
+// e.g. Windows, Plan 9 and js/wasm, don't lock the file at all, so a DB file
+// must not be opened by several processes there.
+package lock
+
+import (
+	"os"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// retryInterval is how often a busy lock is retried until the busy timeout.
+const retryInterval = 10 * time.Millisecond
+
+// Lock acquires a lock on f, which is exclusive for writers and shared for
+// readers. When the file is locked by another process, it's retried until
+// timeout and then engine.ErrLocked is returned. The lock is released when f
+// is closed.
+func Lock(f *os.File, exclusive bool, timeout time.Duration) error {
+	return Retry(func() (bool, error) { return tryLock(f, exclusive) }, timeout)
+}
+
+// Retry calls tryLock until it takes the lock, fails or timeout is passed and
+// then returns engine.ErrLocked. It's the waiting of Lock for other kinds of
+// locks.
+func Retry(tryLock func() (bool, error), timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock()
+		if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return engine.ErrLocked
+		}
+
+		time.Sleep(retryInterval)
+	}
+}
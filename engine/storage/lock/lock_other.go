@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package lock
+
+import "os"
+
+// tryLock doesn't lock on platforms without flock, the file is taken as locked
+// so a single process still works. See the package doc.
+func tryLock(f *os.File, exclusive bool) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,56 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+func open(t *testing.T, path string) *os.File {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	return f
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	reader1, reader2, writer := open(t, path), open(t, path), open(t, path)
+	if err := Lock(reader1, false, 0); err != nil {
+		t.Fatalf("Lock() shared error = %v", err)
+	}
+	if err := Lock(reader2, false, 0); err != nil {
+		t.Fatalf("Lock() second shared error = %v", err)
+	}
+
+	start := time.Now()
+	if err := Lock(writer, true, 50*time.Millisecond); !errors.Is(err, engine.ErrLocked) {
+		t.Fatalf("Lock() exclusive on shared error = %v, want %v", err, engine.ErrLocked)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Lock() returned after %s, want to wait for the busy timeout", elapsed)
+	}
+
+	// the lock is acquired as soon as the readers release it
+	reader1.Close()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		reader2.Close()
+	}()
+	if err := Lock(writer, true, time.Second); err != nil {
+		t.Fatalf("Lock() exclusive after readers error = %v", err)
+	}
+	if err := Lock(open(t, path), false, 0); !errors.Is(err, engine.ErrLocked) {
+		t.Errorf("Lock() shared on exclusive error = %v, want %v", err, engine.ErrLocked)
+	}
+}
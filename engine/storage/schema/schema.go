@@ -0,0 +1,104 @@
+// Package schema keeps the schema of the table of a DB file in its first
+// page, the header page, so it's written and locked with the rows. Columns
+// which ALTER TABLE adds are records of the row format in it, rows aren't
+// rewritten when columns are added.
+package schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/utils"
+)
+
+// Magic starts the header page. DB files which don't start with it were
+// written before the header page and are legacy files.
+const Magic = "sqltut format 2\x00"
+
+// ErrLegacy is the error of writes and backups of legacy files. Their rows
+// have slots of an older size, so they can be read, e.g. by migrate, but
+// nothing else.
+var ErrLegacy = engine.NewError(engine.ExecuteReadOnly, fmt.Errorf("DB file has an older format, migrate it to a new file to write to it"))
+
+var order = binary.LittleEndian
+
+// IsHeader reports whether page is a header page.
+func IsHeader(page []byte) bool {
+	return bytes.HasPrefix(page, []byte(Magic))
+}
+
+// Encode writes the header page of a table of columns into page. The page
+// isn't changed if the columns don't fit it.
+//
+// The header page is Magic, the number of the added columns as a uint16 and
+// for each of them the size of its record as a uint16 and the record, which
+// has the name, 1 for NOT NULL or 0 and the default value.
+func Encode(page []byte, columns []engine.Column) error {
+	added := columns[len(engine.Schema):]
+	header := append([]byte(Magic), 0, 0)
+	order.PutUint16(header[len(Magic):], uint16(len(added)))
+	for i, c := range added {
+		notNull := int64(0)
+		if c.NotNull {
+			notNull = 1
+		}
+		record := utils.Serialize(order, engine.NewRow(uint32(i), engine.NewText(c.Name), engine.NewInteger(notNull), c.Default))
+
+		header = append(header, 0, 0)
+		order.PutUint16(header[len(header)-2:], uint16(len(record)))
+		header = append(header, record...)
+	}
+	if len(header) > len(page) {
+		return engine.NewError(engine.ExecuteTableFull, fmt.Errorf("Schema of %d bytes doesn't fit the header page of %d bytes", len(header), len(page)))
+	}
+
+	copy(page, header)
+	for i := len(header); i < len(page); i++ {
+		page[i] = 0
+	}
+
+	return nil
+}
+
+// Decode returns the columns of the table of a header page.
+func Decode(page []byte) ([]engine.Column, error) {
+	if !IsHeader(page) {
+		return nil, corrupt("Invalid header page")
+	}
+	data := page[len(Magic):]
+	if len(data) < 2 {
+		return nil, corrupt("Truncated header page")
+	}
+	numColumns := int(order.Uint16(data))
+	data = data[2:]
+
+	columns := append([]engine.Column(nil), engine.Schema...)
+	for i := 0; i < numColumns; i++ {
+		if len(data) < 2 || int(order.Uint16(data)) > len(data)-2 {
+			return nil, corrupt("Column %d overflows the header page", i)
+		}
+		size := int(order.Uint16(data))
+		row, err := utils.Deserialize(order, data[2:2+size])
+		if err != nil {
+			return nil, err
+		}
+		data = data[2+size:]
+
+		if row.Id != uint32(i) || len(row.Fields) != 3 {
+			return nil, corrupt("Invalid record of column %d", i)
+		}
+		name, notNull := row.Fields[0], row.Fields[1]
+		if name.Type() != engine.ValueText || name.Len() == 0 || notNull.Type() != engine.ValueInteger || notNull.Int() > 1 || notNull.Int() < 0 {
+			return nil, corrupt("Invalid column %d", i)
+		}
+		columns = append(columns, engine.Column{Name: name.Text(), NotNull: notNull.Int() == 1, Default: row.Fields[2]})
+	}
+
+	return columns, nil
+}
+
+func corrupt(format string, args ...interface{}) error {
+	return engine.NewError(engine.ExecuteCorrupt, fmt.Errorf(format, args...))
+}
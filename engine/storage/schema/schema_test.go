@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+func TestEncode(t *testing.T) {
+	columns := append(append([]engine.Column(nil), engine.Schema...),
+		engine.Column{Name: "age"},
+		engine.Column{Name: "nick", NotNull: true, Default: engine.NewText("no one")},
+		engine.Column{Name: "score", Default: engine.NewReal(-1.5)},
+	)
+	page := make([]byte, 4096)
+	if err := Encode(page, columns); err != nil {
+		t.Fatal(err)
+	}
+	if !IsHeader(page) {
+		t.Errorf("IsHeader() = false, want true")
+	}
+	if got, err := Decode(page); err != nil || !reflect.DeepEqual(got, columns) {
+		t.Errorf("Decode() = %v, %v, want %v", got, err, columns)
+	}
+
+	// columns which don't fit leave the page as it was
+	for i := 0; len(columns) < 100; i++ {
+		columns = append(columns, engine.Column{Name: fmt.Sprintf("c%d", i), Default: engine.NewText(strings.Repeat("a", 255))})
+	}
+	before := append([]byte(nil), page...)
+	if err := Encode(page, columns); !errors.Is(err, engine.ErrTableFull) || !reflect.DeepEqual(page, before) {
+		t.Errorf("Encode() of too many columns error = %v, want %v", err, engine.ErrTableFull)
+	}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	page := make([]byte, 64)
+	if err := Encode(page, append(append([]engine.Column(nil), engine.Schema...), engine.Column{Name: "age"})); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(page []byte)
+	}{
+		{name: "not a header", change: func(page []byte) { page[0] = 0 }},
+		{name: "column overflows the page", change: func(page []byte) { page[len(Magic)] = 2 }},
+		{name: "record overflows the page", change: func(page []byte) { page[len(Magic)+2] = 0xff }},
+		{name: "invalid record", change: func(page []byte) { page[len(Magic)+4] = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupted := append([]byte(nil), page...)
+			tt.change(corrupted)
+			if got, err := Decode(corrupted); !errors.Is(err, engine.ErrCorrupt) {
+				t.Errorf("Decode() = %v, %v, want %v", got, err, engine.ErrCorrupt)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package vfs
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// ErrFault is the error of the operations which are failed by Faulty.
+var ErrFault = errors.New("vfs: injected fault")
+
+// Faulty wraps a VFS and fails operations of the files it opens at chosen
+// points, so durability can be tested deterministically. Writes and syncs are
+// counted from 1 across all its files and a point of 0 never fails.
+type Faulty struct {
+	engine.VFS
+
+	// FailWrite fails the nth write without writing anything.
+	FailWrite int
+	// TearWrite writes only the first TearBytes bytes of the nth write and
+	// then fails it, like a crash in the middle of a write.
+	TearWrite int
+	TearBytes int
+	// FailSync fails the nth sync.
+	FailSync int
+	// FailRead fails the nth read.
+	FailRead int
+	// ShortIO makes every read and write move at most one byte, without an
+	// error, to exercise the handling of short reads and writes.
+	ShortIO bool
+
+	mu     sync.Mutex
+	writes int
+	syncs  int
+	reads  int
+}
+
+func (f *Faulty) Open(name string, readOnly bool) (engine.File, error) {
+	file, err := f.VFS.Open(name, readOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyFile{File: file, vfs: f}, nil
+}
+
+// Writes returns the number of writes so far, failed ones included.
+func (f *Faulty) Writes() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.writes
+}
+
+// Reads returns the number of reads so far, failed ones included.
+func (f *Faulty) Reads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.reads
+}
+
+// Syncs returns the number of syncs so far, failed ones included.
+func (f *Faulty) Syncs() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.syncs
+}
+
+type faultyFile struct {
+	engine.File
+	vfs *Faulty
+}
+
+func (f *faultyFile) ReadAt(p []byte, off int64) (int, error) {
+	f.vfs.mu.Lock()
+	f.vfs.reads++
+	fail, short := f.vfs.reads == f.vfs.FailRead, f.vfs.ShortIO
+	f.vfs.mu.Unlock()
+
+	if fail {
+		return 0, ErrFault
+	}
+	if short && len(p) > 1 {
+		return f.File.ReadAt(p[:1], off)
+	}
+
+	return f.File.ReadAt(p, off)
+}
+
+func (f *faultyFile) WriteAt(p []byte, off int64) (int, error) {
+	f.vfs.mu.Lock()
+	f.vfs.writes++
+	n := f.vfs.writes
+	fail, tear, tearBytes, short := f.vfs.FailWrite, f.vfs.TearWrite, f.vfs.TearBytes, f.vfs.ShortIO
+	f.vfs.mu.Unlock()
+
+	switch n {
+	case fail:
+		return 0, ErrFault
+	case tear:
+		if tearBytes > len(p) {
+			tearBytes = len(p)
+		}
+		written, err := f.File.WriteAt(p[:tearBytes], off)
+		if err != nil {
+			return written, err
+		}
+		return written, ErrFault
+	}
+	if short && len(p) > 1 {
+		return f.File.WriteAt(p[:1], off)
+	}
+
+	return f.File.WriteAt(p, off)
+}
+
+func (f *faultyFile) Sync() error {
+	f.vfs.mu.Lock()
+	f.vfs.syncs++
+	fail := f.vfs.syncs == f.vfs.FailSync
+	f.vfs.mu.Unlock()
+
+	if fail {
+		return ErrFault
+	}
+
+	return f.File.Sync()
+}
@@ -0,0 +1,209 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/lock"
+)
+
+// Memory is a file system which keeps files in memory. Files outlive their
+// handles, so a DB can be closed and opened again, and are locked like the
+// OS ones between the handles of the same Memory.
+type Memory struct {
+	mu    sync.Mutex
+	files map[string]*memoryData
+}
+
+// NewMemory returns an empty in memory file system.
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]*memoryData)}
+}
+
+type memoryData struct {
+	mu   sync.RWMutex
+	data []byte
+	// readers is the number of shared locks, or -1 if it's locked
+	// exclusively. It's guarded by mu too.
+	readers int
+}
+
+func (m *Memory) Open(name string, readOnly bool) (engine.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.files[name]
+	if !ok {
+		if readOnly {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		d = &memoryData{}
+		m.files[name] = d
+	}
+
+	return &memoryFile{name: name, d: d, readOnly: readOnly}, nil
+}
+
+// Remove deletes the file of name.
+func (m *Memory) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+
+	return nil
+}
+
+// Rename moves the file of oldname to newname, replacing it if it exists.
+// Handles of the files keep their data.
+func (m *Memory) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.files[oldname]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrNotExist}
+	}
+	delete(m.files, oldname)
+	m.files[newname] = d
+
+	return nil
+}
+
+var errReadOnly = errors.New("vfs: file is read only")
+
+type memoryFile struct {
+	name     string
+	d        *memoryData
+	readOnly bool
+	// locked is 0 when the handle doesn't have a lock, 1 for a shared and 2
+	// for an exclusive one. It's guarded by d.mu.
+	locked int
+	closed bool
+}
+
+func (f *memoryFile) ReadAt(p []byte, off int64) (int, error) {
+	f.d.mu.RLock()
+	defer f.d.mu.RUnlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if off >= int64(len(f.d.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.d.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (f *memoryFile) WriteAt(p []byte, off int64) (int, error) {
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.readOnly {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: errReadOnly}
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	if end := off + int64(len(p)); end > int64(len(f.d.data)) {
+		f.d.data = append(f.d.data, make([]byte, end-int64(len(f.d.data)))...)
+	}
+
+	return copy(f.d.data[off:], p), nil
+}
+
+func (f *memoryFile) Sync() error {
+	return nil
+}
+
+func (f *memoryFile) Truncate(size int64) error {
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+
+	if f.readOnly {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: errReadOnly}
+	}
+	if size < 0 {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	if size <= int64(len(f.d.data)) {
+		f.d.data = f.d.data[:size]
+	} else {
+		f.d.data = append(f.d.data, make([]byte, size-int64(len(f.d.data)))...)
+	}
+
+	return nil
+}
+
+func (f *memoryFile) Size() (int64, error) {
+	f.d.mu.RLock()
+	defer f.d.mu.RUnlock()
+
+	return int64(len(f.d.data)), nil
+}
+
+func (f *memoryFile) Lock(exclusive bool, timeout time.Duration) error {
+	return lock.Retry(func() (bool, error) {
+		f.d.mu.Lock()
+		defer f.d.mu.Unlock()
+
+		// release the lock of the handle first, like flock converts locks
+		f.unlock()
+		switch {
+		case exclusive && f.d.readers == 0:
+			f.d.readers, f.locked = -1, 2
+		case !exclusive && f.d.readers >= 0:
+			f.d.readers++
+			f.locked = 1
+		default:
+			return false, nil
+		}
+
+		return true, nil
+	}, timeout)
+}
+
+// unlock releases the lock of the handle, if any. d.mu must be held.
+func (f *memoryFile) unlock() {
+	switch f.locked {
+	case 1:
+		f.d.readers--
+	case 2:
+		f.d.readers = 0
+	}
+	f.locked = 0
+}
+
+func (f *memoryFile) Close() error {
+	f.d.mu.Lock()
+	defer f.d.mu.Unlock()
+
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.unlock()
+	f.closed = true
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package vfs
+
+import (
+	"os"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/lock"
+)
+
+// OS is the file system of the OS, files are locked by advisory locks.
+var OS engine.VFS = osFS{}
+
+type osFS struct{}
+
+func (osFS) Open(name string, readOnly bool) (engine.File, error) {
+	flag := os.O_RDWR | os.O_CREATE
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	f, err := os.OpenFile(name, flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	return osFile{f}, nil
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFS) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+type osFile struct {
+	*os.File
+}
+
+func (f osFile) Size() (int64, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	return stat.Size(), nil
+}
+
+func (f osFile) Lock(exclusive bool, timeout time.Duration) error {
+	return lock.Lock(f.File, exclusive, timeout)
+}
@@ -0,0 +1,150 @@
+// Package vfs has the implementations of engine.VFS: the file system of the
+// OS, an in memory one and a wrapper which injects faults.
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// ErrSameFile is the error of writing a copy of an open DB file over itself.
+var ErrSameFile = errors.New("vfs: destination is the open DB file")
+
+// Open opens and locks the DB file of a storage. engine.MemoryPath is a new in
+// memory file and other names are opened by opts.VFS, or the OS if it's nil.
+// The lock is exclusive unless opts.ReadOnly.
+func Open(name string, opts engine.Options) (engine.File, error) {
+	fs := FS(opts)
+	if name == engine.MemoryPath {
+		fs = NewMemory()
+	}
+
+	f, err := fs.Open(name, opts.ReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Lock(!opts.ReadOnly, opts.BusyTimeout); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// FS returns opts.VFS, or the OS if it's nil.
+func FS(opts engine.Options) engine.VFS {
+	if opts.VFS == nil {
+		return OS
+	}
+
+	return opts.VFS
+}
+
+// WriteFile writes the file of name through fs by write. It's written into a
+// temporary file which replaces name once it's synced, so name has either its
+// old content or the whole new one.
+func WriteFile(fs engine.VFS, name string, write func(f engine.File) error) error {
+	tmp := name + ".tmp"
+	f, err := fs.Open(tmp, false)
+	if err != nil {
+		return err
+	}
+
+	// a failed write may have left the temporary file behind
+	err = f.Truncate(0)
+	if err == nil {
+		err = write(f)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fs.Remove(tmp)
+		return err
+	}
+
+	return fs.Rename(tmp, name)
+}
+
+// SameFile reports whether name is the file f opened by vfs, through any of
+// its paths. It's false when name doesn't exist.
+func SameFile(vfs engine.VFS, f engine.File, name string) (bool, error) {
+	if faulty, ok := vfs.(*Faulty); ok {
+		vfs = faulty.VFS
+	}
+	if faulty, ok := f.(*faultyFile); ok {
+		f = faulty.File
+	}
+
+	switch f := f.(type) {
+	case osFile:
+		if vfs != OS {
+			return false, nil
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			return false, err
+		}
+		other, err := os.Stat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return os.SameFile(stat, other), nil
+	case *memoryFile:
+		m, ok := vfs.(*Memory)
+		if !ok {
+			return false, nil
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.files[name] == f.d, nil
+	}
+
+	return false, nil
+}
+
+// ReadAt reads len(p) bytes of f at off. Short reads without an error, which
+// io.ReaderAt allows to be rare but not impossible, are retried. The error is
+// io.EOF when f ends before p is full, n is the number of bytes read anyway.
+func ReadAt(f io.ReaderAt, p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		m, err := f.ReadAt(p[n:], off+int64(n))
+		n += m
+		if err != nil {
+			return n, err
+		}
+		if m == 0 {
+			return n, io.ErrNoProgress
+		}
+	}
+
+	return n, nil
+}
+
+// WriteAt writes the whole p into f at off, retrying short writes.
+func WriteAt(f io.WriterAt, p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		m, err := f.WriteAt(p[n:], off+int64(n))
+		n += m
+		if err != nil {
+			return n, err
+		}
+		if m == 0 {
+			return n, io.ErrShortWrite
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,214 @@
+package vfs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+	_ "github.com/meysampg/sqltut/engine/storage/arraylike"
+	"github.com/meysampg/sqltut/engine/storage/btree"
+	"github.com/meysampg/sqltut/engine/storage/vfs"
+)
+
+func TestFiles(t *testing.T) {
+	for name, fs := range map[string]engine.VFS{"os": vfs.OS, "memory": vfs.NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.db")
+			f, err := fs.Open(path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			if _, err := f.WriteAt([]byte("world"), 6); err != nil {
+				t.Fatalf("WriteAt() error = %v", err)
+			}
+			if _, err := f.WriteAt([]byte("hello "), 0); err != nil {
+				t.Fatalf("WriteAt() error = %v", err)
+			}
+			if size, err := f.Size(); size != 11 || err != nil {
+				t.Errorf("Size() = %d, %v, want 11", size, err)
+			}
+
+			// reading past the end is a short read
+			b := make([]byte, 8)
+			if n, err := f.ReadAt(b, 6); n != 5 || err != io.EOF || string(b[:n]) != "world" {
+				t.Errorf("ReadAt() = %d %q, %v, want 5 %q, %v", n, b[:n], err, "world", io.EOF)
+			}
+
+			if err := f.Truncate(5); err != nil {
+				t.Fatalf("Truncate() error = %v", err)
+			}
+			if err := f.Sync(); err != nil {
+				t.Errorf("Sync() error = %v", err)
+			}
+			if n, err := f.ReadAt(b, 0); n != 5 || string(b[:n]) != "hello" {
+				t.Errorf("ReadAt() after Truncate() = %d %q, %v, want 5 %q", n, b[:n], err, "hello")
+			}
+
+			if _, err := fs.Open(filepath.Join(t.TempDir(), "missing.db"), true); err == nil {
+				t.Errorf("Open() of a missing read only file error = nil")
+			}
+
+			renamed := path + ".renamed"
+			if err := fs.Rename(path, renamed); err != nil {
+				t.Fatalf("Rename() error = %v", err)
+			}
+			if _, err := fs.Open(path, true); err == nil {
+				t.Errorf("Open() of a renamed file error = nil")
+			}
+			g, err := fs.Open(renamed, true)
+			if err != nil {
+				t.Fatalf("Open() after Rename() error = %v", err)
+			}
+			defer g.Close()
+			if size, err := g.Size(); size != 5 || err != nil {
+				t.Errorf("Size() after Rename() = %d, %v, want 5", size, err)
+			}
+		})
+	}
+}
+
+func TestMemoryLock(t *testing.T) {
+	fs := vfs.NewMemory()
+	open := func() engine.File {
+		f, err := fs.Open("test.db", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	reader1, reader2, writer := open(), open(), open()
+	if err := reader1.Lock(false, 0); err != nil {
+		t.Fatalf("Lock() shared error = %v", err)
+	}
+	if err := reader2.Lock(false, 0); err != nil {
+		t.Fatalf("Lock() second shared error = %v", err)
+	}
+	if err := writer.Lock(true, 20*time.Millisecond); !errors.Is(err, engine.ErrLocked) {
+		t.Fatalf("Lock() exclusive on shared error = %v, want %v", err, engine.ErrLocked)
+	}
+
+	reader1.Close()
+	reader2.Close()
+	if err := writer.Lock(true, 0); err != nil {
+		t.Fatalf("Lock() exclusive after readers are closed error = %v", err)
+	}
+	if err := open().Lock(false, 0); !errors.Is(err, engine.ErrLocked) {
+		t.Errorf("Lock() shared on exclusive error = %v, want %v", err, engine.ErrLocked)
+	}
+}
+
+func TestFaulty(t *testing.T) {
+	row := engine.NewRow(1, engine.NewText("user1"), engine.NewText("person1@example.com"))
+
+	tests := []struct {
+		name    string
+		faulty  *vfs.Faulty
+		wantErr string
+		// wantOpenErr is the error of opening the DB after the failure
+		wantOpenErr bool
+		wantRows    int
+	}{
+		{"no fault", &vfs.Faulty{}, "", false, 1},
+		{"failed write", &vfs.Faulty{FailWrite: 1}, "Error writing", false, 0},
+		{"torn write", &vfs.Faulty{TearWrite: 1, TearBytes: 100}, "Error writing", true, 0},
+		{"failed sync", &vfs.Faulty{FailSync: 1}, "Error syncing", false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := vfs.NewMemory()
+			tt.faulty.VFS = fs
+
+			table, err := btree.DbOpenWithOptions("test.db", engine.Options{VFS: tt.faulty})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := table.Insert(row); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+			err = table.Close()
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Close() error = %v, want %q", err, tt.wantErr)
+			}
+			// the header page and the root are written, a failed write stops
+			// flushing before the sync
+			wantWrites, wantSyncs := 2, 1
+			if tt.faulty.FailWrite+tt.faulty.TearWrite > 0 {
+				wantWrites, wantSyncs = 1, 0
+			}
+			if tt.faulty.Writes() != wantWrites || tt.faulty.Syncs() != wantSyncs {
+				t.Errorf("Writes(), Syncs() = %d, %d, want %d, %d", tt.faulty.Writes(), tt.faulty.Syncs(), wantWrites, wantSyncs)
+			}
+
+			// the file is unlocked and has what reached it
+			table, err = btree.DbOpenWithOptions("test.db", engine.Options{VFS: fs})
+			if tt.wantOpenErr {
+				if err == nil {
+					table.Close()
+					t.Fatalf("DbOpen() of a torn file error = nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DbOpen() error = %v", err)
+			}
+			defer table.Close()
+			rows, err := table.Select(context.Background())
+			if err != nil || len(rows) != tt.wantRows {
+				t.Errorf("Select() = %d rows, %v, want %d rows", len(rows), err, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestShortIO(t *testing.T) {
+	for _, name := range []string{"arraylike", "btree"} {
+		t.Run(name, func(t *testing.T) {
+			fs := vfs.NewMemory()
+			faulty := &vfs.Faulty{VFS: fs, ShortIO: true}
+
+			storage, err := engine.OpenStorage(name, "test.db", engine.Options{VFS: faulty})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i <= 3; i++ {
+				if err := storage.Insert(engine.NewRow(uint32(i), engine.NewText("user"), engine.NewText("person@example.com"))); err != nil {
+					t.Fatalf("Insert() error = %v", err)
+				}
+			}
+			if err := storage.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			// every byte of the rows is written and read back one by one
+			storage, err = engine.OpenStorage(name, "test.db", engine.Options{VFS: faulty})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := storage.Select(context.Background())
+			if err != nil || len(rows) != 3 || rows[2].Fields[1].Text() != "person@example.com" {
+				t.Errorf("Select() = %v, %v, want 3 rows", rows, err)
+			}
+			storage.Close()
+
+			storage, err = engine.OpenStorage(name, "test.db", engine.Options{VFS: faulty})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer storage.Close()
+			// the header page is read by the open, rows are in the page 1
+			faulty.FailRead = faulty.Reads() + 1
+			_, err = storage.Select(context.Background())
+			if !errors.Is(err, vfs.ErrFault) || !strings.Contains(err.Error(), "page 1 at offset 4096") {
+				t.Errorf("Select() with a failed read error = %v, want %v of page 1", err, vfs.ErrFault)
+			}
+		})
+	}
+}
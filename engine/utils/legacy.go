@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"encoding/binary"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// Rows which were written before the record format have a header of uint32s:
+// the offset size, the id size and the size of each of the two TEXT fields,
+// then the id and the fields. They're only read.
+
+// noRecordFields is the number of fields after the id of rows without a record.
+const noRecordFields = 2
+
+func deserializeNoRecord(dec binary.ByteOrder, data []byte) (*engine.Row, error) {
+	const offsetSize = 4
+	word := func(i uint32) (uint32, bool) {
+		if uint64(i+1)*offsetSize > uint64(len(data)) {
+			return 0, false
+		}
+		return dec.Uint32(data[i*offsetSize : (i+1)*offsetSize]), true
+	}
+
+	if header, ok := word(0); !ok || header != offsetSize {
+		return nil, corrupt("Unknown format of a row")
+	}
+	idSize, ok := word(1)
+	if !ok || idSize != offsetSize {
+		return nil, corrupt("Invalid id size %d", idSize)
+	}
+
+	row := &engine.Row{Fields: make([]engine.Value, noRecordFields)}
+	offset := uint64(2+noRecordFields) * offsetSize
+	if offset+uint64(idSize) > uint64(len(data)) {
+		return nil, corrupt("Row is truncated in its id")
+	}
+	row.Id = dec.Uint32(data[offset : offset+uint64(idSize)])
+	offset += uint64(idSize)
+
+	for i := range row.Fields {
+		fieldSize, ok := word(2 + uint32(i))
+		if !ok || offset+uint64(fieldSize) > uint64(len(data)) {
+			return nil, corrupt("Field %d overflows the row", i+1)
+		}
+		row.Fields[i] = engine.NewText(string(data[offset : offset+uint64(fieldSize)]))
+		offset += uint64(fieldSize)
+	}
+
+	return row, nil
+}
@@ -2,57 +2,192 @@ package utils
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
 
 	"github.com/meysampg/sqltut/engine"
 )
 
+// recordVersion is the first byte of rows in the record format. Rows of the
+// older format start with their offset size, which is 0 in big endian and 4 in
+// little endian, so they're told apart by it.
+const recordVersion byte = 2
+
+// Serial types of values in the header of a record, like the ones of SQLite.
+// INTEGERs are stored in the smallest of 1, 2, 4 or 8 bytes and 0 and 1 in no
+// bytes at all. TEXTs and BLOBs have odd and even types from their length.
+const (
+	serialNull  uint64 = 0
+	serialInt8  uint64 = 1
+	serialInt16 uint64 = 2
+	serialInt32 uint64 = 4
+	serialInt64 uint64 = 6
+	serialReal  uint64 = 7
+	serialZero  uint64 = 8
+	serialOne   uint64 = 9
+	serialBlob  uint64 = 12
+	serialText  uint64 = 13
+)
+
+func serialType(v engine.Value) uint64 {
+	switch v.Type() {
+	case engine.ValueInteger:
+		switch i := v.Int(); {
+		case i == 0:
+			return serialZero
+		case i == 1:
+			return serialOne
+		case i >= math.MinInt8 && i <= math.MaxInt8:
+			return serialInt8
+		case i >= math.MinInt16 && i <= math.MaxInt16:
+			return serialInt16
+		case i >= math.MinInt32 && i <= math.MaxInt32:
+			return serialInt32
+		}
+		return serialInt64
+	case engine.ValueReal:
+		return serialReal
+	case engine.ValueText:
+		return serialText + 2*uint64(v.Len())
+	case engine.ValueBlob:
+		return serialBlob + 2*uint64(v.Len())
+	}
+
+	return serialNull
+}
+
+// serialSize returns the number of bytes of a value of the serial type. It's
+// false for the reserved types.
+func serialSize(t uint64) (uint32, bool) {
+	switch t {
+	case serialNull, serialZero, serialOne:
+		return 0, true
+	case serialInt8:
+		return 1, true
+	case serialInt16:
+		return 2, true
+	case serialInt32:
+		return 4, true
+	case serialInt64, serialReal:
+		return 8, true
+	}
+	if t < serialBlob || (t-serialBlob)/2 > math.MaxUint32 {
+		return 0, false
+	}
+
+	return uint32((t - serialBlob) / 2), true
+}
+
+// Serialize encodes a row as a record: the version byte, a header of its size
+// and the serial type of each value as varints, then the values, the id first.
+// A record may have fewer values than the columns of its table, when columns
+// were added after it was written.
 func Serialize(enc binary.ByteOrder, row *engine.Row) []byte {
-	var serializedRow []byte
 	size := NewSize(row)
-	if size.RowSize == 0 {
-		return serializedRow
-	}
-
-	serializedRow = make([]byte, size.RowSize, size.RowSize)
-	// offset size
-	enc.PutUint32(serializedRow[:size.OffsetSize], size.OffsetSize)
-	// id size
-	enc.PutUint32(serializedRow[size.OffsetSize:2*size.OffsetSize], size.IdSize)
-	// username size
-	enc.PutUint32(serializedRow[2*size.OffsetSize:3*size.OffsetSize], size.UsernameSize)
-	// email size
-	enc.PutUint32(serializedRow[3*size.OffsetSize:4*size.OffsetSize], size.EmailSize)
-
-	// id
-	enc.PutUint32(serializedRow[size.IdOffset:size.UsernameOffset], row.Id)
-	// username
-	copy(serializedRow[size.UsernameOffset:size.EmailOffset], row.Username)
-	// email
-	copy(serializedRow[size.EmailOffset:], row.Email)
+	serializedRow := make([]byte, size.RowSize, size.RowSize)
+
+	serializedRow[0] = recordVersion
+	offset := 1 + binary.PutUvarint(serializedRow[1:], uint64(size.HeaderSize))
+	for _, t := range size.Types {
+		offset += binary.PutUvarint(serializedRow[offset:], t)
+	}
+
+	for i, v := range row.Values() {
+		value := serializedRow[offset : offset+int(size.ValueSizes[i])]
+		switch size.Types[i] {
+		case serialInt8:
+			value[0] = byte(v.Int())
+		case serialInt16:
+			enc.PutUint16(value, uint16(v.Int()))
+		case serialInt32:
+			enc.PutUint32(value, uint32(v.Int()))
+		case serialInt64:
+			enc.PutUint64(value, uint64(v.Int()))
+		case serialReal:
+			enc.PutUint64(value, math.Float64bits(v.Float()))
+		default:
+			copy(value, v.Text())
+		}
+		offset += len(value)
+	}
 
 	return serializedRow
 }
 
-func Deserialize(dec binary.ByteOrder, data []byte) *engine.Row {
+// Deserialize decodes a row of the record format or of the older ones.
+// Malformed rows fail with engine.ErrCorrupt. Bytes after the row are ignored,
+// so it can be given a whole slot of a page.
+func Deserialize(dec binary.ByteOrder, data []byte) (*engine.Row, error) {
 	if len(data) == 0 {
-		return nil
-	}
-	row := &engine.Row{}
-
-	offsetSize := dec.Uint32(data[:SizeOf(uint32(1))])
-	idSize := dec.Uint32(data[offsetSize : 2*offsetSize])
-	idOffset := 4 * offsetSize
-	usernameSize := dec.Uint32(data[2*offsetSize : 3*offsetSize])
-	usernameOffset := idOffset + idSize
-	emailSize := dec.Uint32(data[3*offsetSize : 4*offsetSize])
-	emailOffset := usernameOffset + usernameSize
-
-	// id
-	row.Id = dec.Uint32(data[idOffset:usernameOffset])
-	// email
-	row.Username = string(data[usernameOffset:emailOffset])
-	// username
-	row.Email = string(data[emailOffset : emailOffset+emailSize])
-
-	return row
+		return nil, corrupt("Empty row")
+	}
+	if data[0] != recordVersion {
+		return deserializeNoRecord(dec, data)
+	}
+
+	headerSize, n := binary.Uvarint(data[1:])
+	if n <= 0 || headerSize < uint64(n) || headerSize > uint64(len(data)-1) {
+		return nil, corrupt("Invalid header size %d of a row of %d bytes", headerSize, len(data))
+	}
+	header := data[1+n : 1+headerSize]
+	body := data[1+headerSize:]
+
+	var values []engine.Value
+	for len(header) > 0 {
+		t, n := binary.Uvarint(header)
+		if n <= 0 {
+			return nil, corrupt("Invalid serial type at byte %d of the header", len(data)-len(header))
+		}
+		header = header[n:]
+
+		size, ok := serialSize(t)
+		if !ok {
+			return nil, corrupt("Invalid serial type %d", t)
+		}
+		if uint64(size) > uint64(len(body)) {
+			return nil, corrupt("Value of %d bytes overflows the row", size)
+		}
+		values = append(values, decodeValue(dec, t, body[:size]))
+		body = body[size:]
+	}
+
+	if len(values) == 0 {
+		return nil, corrupt("Row has no id")
+	}
+	id := values[0]
+	if id.Type() != engine.ValueInteger || id.Int() < 0 || id.Int() > math.MaxUint32 {
+		return nil, corrupt("Invalid id %s", id)
+	}
+
+	return engine.NewRow(uint32(id.Int()), values[1:]...), nil
+}
+
+func decodeValue(dec binary.ByteOrder, t uint64, value []byte) engine.Value {
+	switch t {
+	case serialNull:
+		return engine.Null
+	case serialZero:
+		return engine.NewInteger(0)
+	case serialOne:
+		return engine.NewInteger(1)
+	case serialInt8:
+		return engine.NewInteger(int64(int8(value[0])))
+	case serialInt16:
+		return engine.NewInteger(int64(int16(dec.Uint16(value))))
+	case serialInt32:
+		return engine.NewInteger(int64(int32(dec.Uint32(value))))
+	case serialInt64:
+		return engine.NewInteger(int64(dec.Uint64(value)))
+	case serialReal:
+		return engine.NewReal(math.Float64frombits(dec.Uint64(value)))
+	}
+	if t%2 == 0 {
+		return engine.NewBlob(value)
+	}
+
+	return engine.NewText(string(value))
+}
+
+func corrupt(format string, args ...interface{}) error {
+	return engine.NewError(engine.ExecuteCorrupt, fmt.Errorf(format, args...))
 }
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"encoding/binary"
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/meysampg/sqltut/engine"
@@ -19,14 +21,20 @@ func TestSerialize(t *testing.T) {
 		{
 			name: "bytes slice size equal to row size",
 			args: args{
-				row: &engine.Row{
-					Id:       4,
-					Username: "meysampg",
-					Email:    "myemail@domain.com",
-				},
+				row: engine.NewRow(4, engine.NewText("meysampg"), engine.NewText("myemail@domain.com")),
 			},
 			want: nil,
 		},
+		{
+			name: "record of each serial type",
+			args: args{
+				row: engine.NewRow(300, engine.Null, engine.NewInteger(1), engine.NewInteger(-2), engine.NewReal(0.5), engine.NewText("ab"), engine.NewBlob([]byte{7})),
+			},
+			want: []byte{
+				2, 8, 2, 0, 9, 1, 7, 17, 14,
+				1, 44, 0xfe, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0, 'a', 'b', 7,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -35,6 +43,9 @@ func TestSerialize(t *testing.T) {
 			if len(got) != int(size.RowSize) {
 				t.Errorf("Serialized size = %v, want %v", len(got), size.RowSize)
 			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Serialize() = %v, want %v", got, tt.want)
+			}
 		})
 	}
 }
@@ -46,18 +57,128 @@ func TestDeserialize(t *testing.T) {
 	}{
 		{
 			name: "Serdes works properly",
-			want: &engine.Row{
-				Id:       5,
-				Username: "meysampg",
-				Email:    "myemail@domain.com",
+			want: engine.NewRow(5, engine.NewText("meysampg"), engine.NewText("myemail@domain.com")),
+		},
+		{
+			name: "Typed values",
+			want: engine.NewRow(6, engine.NewInteger(-42), engine.NewReal(3.5), engine.NewInteger(1<<40)),
+		},
+		{
+			name: "NULL and BLOB",
+			want: engine.NewRow(7, engine.Null, engine.NewBlob([]byte{0, 1, 0xff})),
+		},
+		{
+			name: "Only the id",
+			want: &engine.Row{Id: 1<<32 - 1, Fields: []engine.Value{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// rows are read from slots which are larger than them
+			data := append(Serialize(binary.BigEndian, tt.want), 0, 0, 0)
+			if got, err := Deserialize(binary.BigEndian, data); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Deserialize() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeserializeNoRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want *engine.Row
+	}{
+		{
+			name: "rows which were written before the record format",
+			data: []byte{
+				0, 0, 0, 4, 0, 0, 0, 4, 0, 0, 0, 2, 0, 0, 0, 1,
+				0, 0, 0, 9, 'a', 'b', 'c',
+			},
+			want: engine.NewRow(9, engine.NewText("ab"), engine.NewText("c")),
+		},
+		{
+			name: "empty fields",
+			data: []byte{
+				0, 0, 0, 4, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 1,
 			},
+			want: engine.NewRow(1, engine.NewText(""), engine.NewText("")),
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := Deserialize(binary.BigEndian, Serialize(binary.BigEndian, tt.want)); got.String() != tt.want.String() {
-				t.Errorf("Deserialize() = %v, want %v", got, tt.want)
+			if got, err := Deserialize(binary.BigEndian, tt.data); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Deserialize() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeserializeCorrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "header size overflows the row", data: []byte{2, 9, 1}},
+		{name: "no id", data: []byte{2, 1}},
+		{name: "reserved serial type", data: []byte{2, 2, 10}},
+		{name: "value overflows the row", data: []byte{2, 3, 8, 17, 'a'}},
+		{name: "text id", data: []byte{2, 2, 15, 'a'}},
+		{name: "negative id", data: []byte{2, 2, 1, 0xff}},
+		{name: "truncated varint", data: []byte{2, 3, 8, 0x80}},
+		{name: "unknown format", data: []byte{0, 0, 0, 8, 0, 0, 0, 4}},
+		{name: "truncated header", data: []byte{0, 0, 0, 4, 0, 0}},
+		{name: "field overflows the row", data: []byte{0, 0, 0, 4, 0, 0, 0, 4, 0, 0, 0, 0xff, 0, 0, 0, 1, 0, 0, 0, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Deserialize(binary.BigEndian, tt.data); !errors.Is(err, engine.ErrCorrupt) {
+				t.Errorf("Deserialize() = %v, %v, want %v", got, err, engine.ErrCorrupt)
 			}
 		})
 	}
 }
+
+func FuzzSerdes(f *testing.F) {
+	f.Add(uint32(1), int64(-2), 0.5, "user1", []byte{0xff}, false)
+	f.Add(uint32(1<<32-1), int64(1<<40), -1e300, "", []byte(nil), true)
+	f.Fuzz(func(t *testing.T, id uint32, i int64, r float64, s string, b []byte, null bool) {
+		row := engine.NewRow(id, engine.NewInteger(i), engine.NewReal(r), engine.NewText(s), engine.NewBlob(b))
+		if null {
+			row.Fields = append(row.Fields, engine.Null)
+		}
+
+		for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+			data := Serialize(order, row)
+			if size := NewSize(row); len(data) != int(size.RowSize) {
+				t.Fatalf("Serialized size = %v, want %v", len(data), size.RowSize)
+			}
+			got, err := Deserialize(order, data)
+			if err != nil || !reflect.DeepEqual(got, row) {
+				t.Fatalf("Deserialize(Serialize(%v)) = %v, %v", row, got, err)
+			}
+		}
+	})
+}
+
+func FuzzDeserialize(f *testing.F) {
+	f.Add(Serialize(binary.BigEndian, engine.NewRow(5, engine.NewText("meysampg"), engine.Null, engine.NewReal(3.5))))
+	f.Add([]byte{0, 0, 0, 4, 0, 0, 0, 4, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 9, 'a', 'b', 'c'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		row, err := Deserialize(binary.BigEndian, data)
+		if err != nil {
+			if !errors.Is(err, engine.ErrCorrupt) {
+				t.Fatalf("Deserialize() error = %v, want %v", err, engine.ErrCorrupt)
+			}
+			return
+		}
+
+		// whatever is decoded is encoded into a record of the same row
+		got, err := Deserialize(binary.BigEndian, Serialize(binary.BigEndian, row))
+		if err != nil || !reflect.DeepEqual(got.Values(), row.Values()) {
+			t.Fatalf("Deserialize(Serialize(%v)) = %v, %v", row, got, err)
+		}
+	})
+}
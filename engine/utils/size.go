@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"encoding/binary"
+	"fmt"
 	"reflect"
 	"unsafe"
 
@@ -29,33 +31,62 @@ func SizeOf(i interface{}) uint32 {
 	}
 }
 
+// RowSize is the size of the slots of rows in DB files. It fits the largest
+// record of a row of engine.Schema: the version, a header of 6 bytes, an id of
+// 8 bytes (ids above MaxInt32 don't fit 4) and two values of 255 bytes.
+// Columns added by ALTER TABLE share the slot.
+const RowSize uint32 = 1 + 6 + 8 + 255 + 255
+
+// Size is the layout of a serialized row: the version byte, the header of
+// the record and the values.
 type Size struct {
-	OffsetSize     uint32
-	IdSize         uint32
-	UsernameSize   uint32
-	EmailSize      uint32
-	IdOffset       uint32
-	UsernameOffset uint32
-	EmailOffset    uint32
-	RowSize        uint32
+	// HeaderSize is the number of bytes of the header, including the varint
+	// of itself.
+	HeaderSize uint32
+	// Types are the serial types of the values, the id first.
+	Types      []uint64
+	ValueSizes []uint32
+	RowSize    uint32
 }
 
 func NewSize(row *engine.Row) *Size {
+	values := row.Values()
 	size := &Size{
-		OffsetSize:     SizeOf(uint32(1)),
-		IdSize:         SizeOf(row.Id),
-		UsernameSize:   SizeOf(row.Username),
-		EmailSize:      SizeOf(row.Email),
-		IdOffset:       0,
-		UsernameOffset: 0,
-		EmailOffset:    0,
-		RowSize:        0,
+		Types:      make([]uint64, len(values)),
+		ValueSizes: make([]uint32, len(values)),
+	}
+
+	typesSize := uint32(0)
+	bodySize := uint32(0)
+	for i, v := range values {
+		size.Types[i] = serialType(v)
+		size.ValueSizes[i], _ = serialSize(size.Types[i])
+		typesSize += uvarintSize(size.Types[i])
+		bodySize += size.ValueSizes[i]
 	}
 
-	size.IdOffset = 4 * size.OffsetSize // first part is offset size and after that we have id, username and email sizes
-	size.UsernameOffset = size.IdOffset + size.IdSize
-	size.EmailOffset = size.UsernameOffset + size.UsernameSize
-	size.RowSize = 4*size.OffsetSize + size.IdSize + size.UsernameSize + size.EmailSize
+	// the size of the header includes its own varint, which may grow the header
+	size.HeaderSize = typesSize + 1
+	for size.HeaderSize != typesSize+uvarintSize(uint64(size.HeaderSize)) {
+		size.HeaderSize = typesSize + uvarintSize(uint64(size.HeaderSize))
+	}
+	size.RowSize = 1 + size.HeaderSize + bodySize
 
 	return size
 }
+
+func uvarintSize(x uint64) uint32 {
+	var buf [binary.MaxVarintLen64]byte
+
+	return uint32(binary.PutUvarint(buf[:], x))
+}
+
+// CheckSize fails with engine.ErrRowTooLarge when the serialized row is
+// larger than max, the size of the slots of rows in a storage.
+func CheckSize(row *engine.Row, max uint32) error {
+	if size := NewSize(row).RowSize; size > max {
+		return engine.NewError(engine.ExecuteRowTooLarge, fmt.Errorf("Row of %d bytes doesn't fit a slot of %d bytes", size, max))
+	}
+
+	return nil
+}
@@ -0,0 +1,470 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ValueType is the storage class of a value, like the ones of SQLite.
+type ValueType uint8
+
+const (
+	ValueNull ValueType = iota
+	ValueInteger
+	ValueReal
+	ValueText
+	ValueBlob
+)
+
+var valueTypeNames = map[ValueType]string{
+	ValueNull:    "NULL",
+	ValueInteger: "INTEGER",
+	ValueReal:    "REAL",
+	ValueText:    "TEXT",
+	ValueBlob:    "BLOB",
+}
+
+func (t ValueType) String() string {
+	if name, ok := valueTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ValueType(%d)", uint8(t))
+}
+
+// Value is a value of a column. The zero value is NULL.
+type Value struct {
+	typ ValueType
+	i   int64
+	f   float64
+	// s keeps the bytes of TEXT and BLOB values
+	s string
+}
+
+// Null is the NULL value.
+var Null = Value{}
+
+func NewInteger(i int64) Value {
+	return Value{typ: ValueInteger, i: i}
+}
+
+// NewReal returns a REAL value, NaN is NULL like in SQLite.
+func NewReal(f float64) Value {
+	if math.IsNaN(f) {
+		return Null
+	}
+
+	return Value{typ: ValueReal, f: f}
+}
+
+func NewText(s string) Value {
+	return Value{typ: ValueText, s: s}
+}
+
+func NewBlob(b []byte) Value {
+	return Value{typ: ValueBlob, s: string(b)}
+}
+
+// ValueOf converts a Go value into a Value. nil is NULL, integers and
+// booleans are INTEGER, floats are REAL, strings are TEXT and byte slices
+// are BLOB.
+func ValueOf(v interface{}) (Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return Null, nil
+	case Value:
+		return v, nil
+	case string:
+		return NewText(v), nil
+	case []byte:
+		return NewBlob(v), nil
+	case bool:
+		if v {
+			return NewInteger(1), nil
+		}
+		return NewInteger(0), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewInteger(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return Null, fmt.Errorf("Value %d overflows INTEGER", rv.Uint())
+		}
+		return NewInteger(int64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return NewReal(rv.Float()), nil
+	}
+
+	return Null, fmt.Errorf("Unsupported type %T", v)
+}
+
+func (v Value) Type() ValueType {
+	return v.typ
+}
+
+func (v Value) IsNull() bool {
+	return v.typ == ValueNull
+}
+
+// Int returns the value of an INTEGER, or a REAL truncated to an integer.
+func (v Value) Int() int64 {
+	if v.typ == ValueReal {
+		return int64(v.f)
+	}
+
+	return v.i
+}
+
+// Float returns the value of a REAL or an INTEGER.
+func (v Value) Float() float64 {
+	if v.typ == ValueInteger {
+		return float64(v.i)
+	}
+
+	return v.f
+}
+
+// Text returns the bytes of a TEXT or a BLOB as a string.
+func (v Value) Text() string {
+	return v.s
+}
+
+// Bytes returns a copy of the bytes of a TEXT or a BLOB.
+func (v Value) Bytes() []byte {
+	if v.typ != ValueText && v.typ != ValueBlob {
+		return nil
+	}
+
+	return []byte(v.s)
+}
+
+// Len returns the number of bytes of a TEXT or a BLOB.
+func (v Value) Len() int {
+	return len(v.s)
+}
+
+// Interface returns the value as nil, int64, float64, string or []byte.
+func (v Value) Interface() interface{} {
+	switch v.typ {
+	case ValueInteger:
+		return v.i
+	case ValueReal:
+		return v.f
+	case ValueText:
+		return v.s
+	case ValueBlob:
+		return []byte(v.s)
+	}
+
+	return nil
+}
+
+// String formats the value for printing. REALs always have a decimal point or
+// an exponent, so they're distinguishable from INTEGERs, and BLOBs are printed
+// as hex literals.
+func (v Value) String() string {
+	switch v.typ {
+	case ValueInteger:
+		return strconv.FormatInt(v.i, 10)
+	case ValueReal:
+		return formatReal(v.f)
+	case ValueText:
+		return v.s
+	case ValueBlob:
+		return "x'" + hex.EncodeToString([]byte(v.s)) + "'"
+	}
+
+	return "NULL"
+}
+
+// Literal formats the value as a literal of a statement which is parsed back
+// into the same value. TEXTs are quoted only when they need to be.
+func (v Value) Literal() string {
+	switch v.typ {
+	case ValueReal:
+		if math.IsInf(v.f, 0) {
+			// overflows to the same infinity when it's parsed
+			return strings.Replace(formatReal(v.f), "Inf", "9e999", 1)
+		}
+	case ValueText:
+		if !isBareText(v.s) {
+			return "'" + strings.ReplaceAll(v.s, "'", "''") + "'"
+		}
+	}
+
+	return v.String()
+}
+
+// MarshalJSON encodes NULL as null, numbers as numbers, TEXT as a string and
+// BLOB as a base64 string like encoding/json does for byte slices. Infinite
+// REALs aren't valid JSON numbers, so they're strings.
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.typ {
+	case ValueInteger:
+		return []byte(strconv.FormatInt(v.i, 10)), nil
+	case ValueReal:
+		if math.IsInf(v.f, 0) {
+			return json.Marshal(formatReal(v.f))
+		}
+		return []byte(strconv.FormatFloat(v.f, 'g', -1, 64)), nil
+	case ValueText:
+		return json.Marshal(v.s)
+	case ValueBlob:
+		return json.Marshal(base64.StdEncoding.EncodeToString([]byte(v.s)))
+	}
+
+	return []byte("null"), nil
+}
+
+// integerAffinity converts the value like a column of INTEGER affinity of
+// SQLite: to an INTEGER if it can be done without losing information, or else
+// numeric TEXTs to REAL.
+func (v Value) integerAffinity() Value {
+	if n, ok := v.Coerce(ValueInteger); ok {
+		return n
+	}
+	if v.typ == ValueText {
+		if n, ok := v.Coerce(ValueReal); ok {
+			return n
+		}
+	}
+
+	return v
+}
+
+// Coerce converts the value to t when it can be done without losing
+// information, like the type affinity of SQLite columns: numeric TEXTs are
+// converted to numbers, integral REALs to INTEGER and numbers to TEXT. It
+// returns the value unchanged and false otherwise. NULL is left as NULL.
+func (v Value) Coerce(t ValueType) (Value, bool) {
+	if v.typ == t || v.typ == ValueNull {
+		return v, true
+	}
+
+	switch t {
+	case ValueInteger:
+		switch v.typ {
+		case ValueReal:
+			if v.f == math.Trunc(v.f) && v.f >= math.MinInt64 && v.f < math.MaxInt64 {
+				return NewInteger(int64(v.f)), true
+			}
+		case ValueText:
+			if n, ok := parseNumber(strings.TrimSpace(v.s)); ok {
+				if n, ok := n.Coerce(t); ok {
+					return n, true
+				}
+			}
+		}
+	case ValueReal:
+		switch v.typ {
+		case ValueInteger:
+			return NewReal(float64(v.i)), true
+		case ValueText:
+			if n, ok := parseNumber(strings.TrimSpace(v.s)); ok {
+				if n, ok := n.Coerce(t); ok {
+					return n, true
+				}
+			}
+		}
+	case ValueText:
+		switch v.typ {
+		case ValueInteger, ValueReal:
+			return NewText(v.String()), true
+		case ValueBlob:
+			return NewText(v.s), true
+		}
+	case ValueBlob:
+		if v.typ == ValueText {
+			return NewBlob([]byte(v.s)), true
+		}
+	}
+
+	return v, false
+}
+
+// Compare returns -1, 0 or +1 when a is less than, equal to or greater than
+// b. Values are ordered like in SQLite: NULL first, then INTEGERs and REALs by
+// their numeric value, then TEXTs and BLOBs by their bytes.
+func Compare(a, b Value) int {
+	if ca, cb := a.class(), b.class(); ca != cb {
+		if ca < cb {
+			return -1
+		}
+		return 1
+	}
+
+	switch a.typ {
+	case ValueNull:
+		return 0
+	case ValueInteger, ValueReal:
+		if a.typ == ValueInteger && b.typ == ValueInteger {
+			return compareInt(a.i, b.i)
+		}
+		return compareNumeric(a, b)
+	}
+
+	return strings.Compare(a.s, b.s)
+}
+
+// class is the rank of the type in the order of Compare.
+func (v Value) class() int {
+	switch v.typ {
+	case ValueNull:
+		return 0
+	case ValueInteger, ValueReal:
+		return 1
+	case ValueText:
+		return 2
+	}
+
+	return 3
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func compareNumeric(a, b Value) int {
+	fa, fb := a.Float(), b.Float()
+	switch {
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	}
+
+	// large INTEGERs aren't exact as floats, so ties are broken by them
+	if a.typ == ValueInteger && b.typ == ValueReal && fb >= math.MinInt64 && fb < math.MaxInt64 {
+		return compareInt(a.i, int64(fb))
+	}
+	if a.typ == ValueReal && b.typ == ValueInteger && fa >= math.MinInt64 && fa < math.MaxInt64 {
+		return compareInt(int64(fa), b.i)
+	}
+
+	return 0
+}
+
+func formatReal(f float64) string {
+	if math.IsInf(f, 1) {
+		return "Inf"
+	} else if math.IsInf(f, -1) {
+		return "-Inf"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return s
+}
+
+// ParseLiteral parses a literal of a statement: NULL, an integer, a real, a
+// quoted 'text', a x'hex' blob or else a bare word which is a TEXT. It's the
+// reverse of Value.Literal.
+func ParseLiteral(text string) (Value, bool) {
+	switch {
+	case text == "":
+		return Null, false
+	case strings.EqualFold(text, "null"):
+		return Null, true
+	case text[0] == '\'':
+		s, ok := unquote(text)
+		return NewText(s), ok
+	case len(text) > 1 && (text[0] == 'x' || text[0] == 'X') && text[1] == '\'':
+		s, ok := unquote(text[1:])
+		if !ok {
+			return Null, false
+		}
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return Null, false
+		}
+		return NewBlob(b), true
+	}
+
+	if v, ok := parseNumber(text); ok {
+		return v, true
+	}
+
+	return NewText(text), true
+}
+
+// parseNumber parses a decimal integer or a real. Integers which overflow
+// int64 are REALs and so are reals out of the range of float64, which are
+// rounded to infinity or zero.
+func parseNumber(text string) (Value, bool) {
+	digits := false
+	for i, r := range text {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case r == '.' || r == 'e' || r == 'E':
+		case (r == '+' || r == '-') && (i == 0 || text[i-1] == 'e' || text[i-1] == 'E'):
+		default:
+			return Null, false
+		}
+	}
+	if !digits {
+		return Null, false
+	}
+
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return NewInteger(i), true
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if e, ok := err.(*strconv.NumError); err != nil && (!ok || e.Err != strconv.ErrRange) {
+		return Null, false
+	}
+
+	return NewReal(f), true
+}
+
+// unquote removes the quotes of a 'text' and unescapes its doubled quotes.
+func unquote(text string) (string, bool) {
+	if len(text) < 2 || text[0] != '\'' || text[len(text)-1] != '\'' {
+		return "", false
+	}
+
+	var b bytes.Buffer
+	inner := text[1 : len(text)-1]
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\'' {
+			if i+1 == len(inner) || inner[i+1] != '\'' {
+				return "", false
+			}
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+
+	return b.String(), true
+}
+
+// isBareText reports whether s is parsed back as the same TEXT without quotes.
+func isBareText(s string) bool {
+	if s == "" || strings.ContainsAny(s[:1], "'?$:") || strings.IndexFunc(s, unicode.IsSpace) >= 0 {
+		return false
+	}
+	v, ok := ParseLiteral(s)
+
+	return ok && v.typ == ValueText && v.s == s
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	// in ascending order, equal values are next to each other
+	values := []Value{
+		Null,
+		NewReal(math.Inf(-1)),
+		NewInteger(-3),
+		NewReal(-2.5),
+		NewInteger(1),
+		NewReal(1),
+		NewInteger(math.MaxInt64 - 1),
+		NewInteger(math.MaxInt64),
+		NewText(""),
+		NewText("a"),
+		NewText("b"),
+		NewBlob(nil),
+		NewBlob([]byte("a")),
+	}
+	for i, a := range values {
+		for j, b := range values {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if i == 4 && j == 5 || i == 5 && j == 4 {
+				want = 0
+			}
+			if got := Compare(a, b); got != want {
+				t.Errorf("Compare(%v, %v) = %v, want %v", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		value  Value
+		typ    ValueType
+		want   Value
+		wantOk bool
+	}{
+		{value: NewReal(3), typ: ValueInteger, want: NewInteger(3), wantOk: true},
+		{value: NewReal(3.5), typ: ValueInteger, want: NewReal(3.5), wantOk: false},
+		{value: NewText(" 12 "), typ: ValueInteger, want: NewInteger(12), wantOk: true},
+		{value: NewText("1e2"), typ: ValueInteger, want: NewInteger(100), wantOk: true},
+		{value: NewText("abc"), typ: ValueInteger, want: NewText("abc"), wantOk: false},
+		{value: NewText("1.5"), typ: ValueInteger, want: NewText("1.5"), wantOk: false},
+		{value: NewText("x"), typ: ValueReal, want: NewText("x"), wantOk: false},
+		{value: NewInteger(2), typ: ValueReal, want: NewReal(2), wantOk: true},
+		{value: NewReal(2.5), typ: ValueText, want: NewText("2.5"), wantOk: true},
+		{value: NewText("ab"), typ: ValueBlob, want: NewBlob([]byte("ab")), wantOk: true},
+		{value: NewBlob([]byte("ab")), typ: ValueInteger, want: NewBlob([]byte("ab")), wantOk: false},
+		{value: Null, typ: ValueInteger, want: Null, wantOk: true},
+	}
+	for _, tt := range tests {
+		got, ok := tt.value.Coerce(tt.typ)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("%v.Coerce(%v) = %v, %v, want %v, %v", tt.value, tt.typ, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		value Value
+		want  string
+	}{
+		{value: Null, want: "NULL"},
+		{value: NewInteger(-7), want: "-7"},
+		{value: NewReal(1), want: "1.0"},
+		{value: NewReal(1e300 * 10), want: "1e+301"},
+		{value: NewReal(math.Inf(-1)), want: "-9e999"},
+		{value: NewText("user1"), want: "user1"},
+		{value: NewText("a b"), want: "'a b'"},
+		{value: NewText("it's"), want: "it's"},
+		{value: NewText("'quoted'"), want: "'''quoted'''"},
+		{value: NewText("12"), want: "'12'"},
+		{value: NewText("null"), want: "'null'"},
+		{value: NewText("?"), want: "'?'"},
+		{value: NewText(""), want: "''"},
+		{value: NewBlob([]byte{0xab, 0}), want: "x'ab00'"},
+	}
+	for _, tt := range tests {
+		got := tt.value.Literal()
+		if got != tt.want {
+			t.Errorf("%v.Literal() = %q, want %q", tt.value, got, tt.want)
+		}
+
+		tokens, err := tokenize([]byte(got))
+		if err != nil || len(tokens) != 1 {
+			t.Fatalf("tokenize(%q) = %v, %v, want one token", got, tokens, err)
+		}
+		if parsed, ok := ParseLiteral(tokens[0].text); !ok || parsed != tt.value {
+			t.Errorf("ParseLiteral(%q) = %v, %v, want %v", got, parsed, ok, tt.value)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"io"
+	"time"
+)
+
+// VFS opens the DB files of storages, so they can be kept somewhere else than
+// the file system of the OS, e.g. in memory, or fail on purpose in tests.
+// Implementations are in the storage/vfs package.
+type VFS interface {
+	// Open opens the file of name, which is created unless it's read only.
+	Open(name string, readOnly bool) (File, error)
+	Remove(name string) error
+	// Rename replaces newname by oldname atomically, like os.Rename.
+	Rename(oldname, newname string) error
+}
+
+// File is a DB file opened by a VFS. Reads and writes are positional, so
+// they don't share an offset.
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+	// Close releases the lock of the file too.
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+	Size() (int64, error)
+	// Lock locks the file, exclusively for writers or shared for readers.
+	// The lock of others is waited for up to timeout before failing with
+	// ErrLocked.
+	Lock(exclusive bool, timeout time.Duration) error
+}
@@ -0,0 +1,156 @@
+package sqltut
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// Rows is the result of a query. Call Next to advance to each row and Scan to
+// read it.
+type Rows struct {
+	ctx    context.Context
+	result *engine.Result
+	err    error
+}
+
+// Columns returns the names of the columns.
+func (r *Rows) Columns() []string {
+	return r.result.Columns
+}
+
+// Next advances to the next row. It returns false at the end of the rows or
+// when the context of the query is done, which is reported by Err.
+func (r *Rows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if err := r.ctx.Err(); err != nil {
+		r.err = err
+		return false
+	}
+
+	return r.result.Next()
+}
+
+// Err returns the error which stopped Next, if any.
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Close discards the remaining rows.
+func (r *Rows) Close() error {
+	for r.result.Next() {
+	}
+
+	return nil
+}
+
+// Scan copies the columns of the current row into dest, which are pointers to
+// integers, floats, strings, byte slices, engine.Values or empty interfaces.
+// NULL can only be scanned into engine.Values and empty interfaces.
+func (r *Rows) Scan(dest ...interface{}) error {
+	row := r.result.Row()
+	if row == nil {
+		return fmt.Errorf("sqltut: Scan called without calling Next")
+	}
+
+	values := row.Values()
+	if len(dest) != len(values) {
+		return fmt.Errorf("sqltut: expected %d destination arguments in Scan, not %d", len(values), len(dest))
+	}
+	for i, value := range values {
+		if err := assign(dest[i], value); err != nil {
+			return fmt.Errorf("sqltut: Scan column %d (%s): %w", i, r.result.Columns[i], err)
+		}
+	}
+
+	return nil
+}
+
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// Row is the result of QueryRow.
+type Row struct {
+	rows *Rows
+	err  error
+}
+
+// Scan copies the columns of the first row into dest. It returns ErrNoRows
+// if there is no row.
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return ErrNoRows
+	}
+
+	return r.rows.Scan(dest...)
+}
+
+func assign(dest interface{}, val engine.Value) error {
+	switch d := dest.(type) {
+	case *engine.Value:
+		*d = val
+		return nil
+	case *interface{}:
+		*d = val.Interface()
+		return nil
+	}
+	value := val.Interface()
+	if value == nil {
+		return fmt.Errorf("converting NULL to %T is unsupported", dest)
+	}
+
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return fmt.Errorf("destination is not a pointer")
+	}
+	d = d.Elem()
+	v := reflect.ValueOf(value)
+
+	switch {
+	case isInteger(d.Kind()) && isInteger(v.Kind()):
+		if isUnsigned(v.Kind()) {
+			if isUnsigned(d.Kind()) && !d.OverflowUint(v.Uint()) || !isUnsigned(d.Kind()) && !d.OverflowInt(int64(v.Uint())) {
+				d.Set(v.Convert(d.Type()))
+				return nil
+			}
+		} else if isUnsigned(d.Kind()) && v.Int() >= 0 && !d.OverflowUint(uint64(v.Int())) || !isUnsigned(d.Kind()) && !d.OverflowInt(v.Int()) {
+			d.Set(v.Convert(d.Type()))
+			return nil
+		}
+		return fmt.Errorf("value %v overflows %s", value, d.Type())
+	case isFloat(d.Kind()) && (isFloat(v.Kind()) || isInteger(v.Kind())):
+		d.Set(v.Convert(d.Type()))
+		return nil
+	case d.Kind() == reflect.String && (v.Kind() == reflect.String || v.Type() == bytesType):
+		d.SetString(val.Text())
+		return nil
+	case d.Type() == bytesType && (v.Kind() == reflect.String || v.Type() == bytesType):
+		d.SetBytes(val.Bytes())
+		return nil
+	}
+
+	return fmt.Errorf("unsupported conversion from %T to %s", value, d.Type())
+}
+
+func isInteger(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Uint64
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+func isUnsigned(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uint64
+}
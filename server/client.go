@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// Client is a connection to a server. It's safe for concurrent use, but
+// statements are sent one at a time.
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// Response is the result of a statement executed by the server.
+type Response struct {
+	Columns      []string
+	Rows         []*engine.Row
+	RowsAffected int64
+}
+
+// Dial connects to the server listening on the TCP address.
+func Dial(addr string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}, nil
+}
+
+// Exec runs the statement on the server. Failures of the statement are
+// returned as *engine.Error, so they can be matched by errors.Is just like
+// local ones.
+func (c *Client) Exec(command []byte) (*Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := writeFrame(c.w, frameQuery, command); err != nil {
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	response := &Response{}
+	for {
+		typ, payload, err := readFrame(c.r)
+		if err != nil {
+			return nil, err
+		}
+		d := newDecoder(payload)
+
+		switch typ {
+		case frameColumns:
+			response.Columns = d.list()
+		case frameRow:
+			values := d.values()
+			if d.err == nil {
+				var row *engine.Row
+				row, d.err = parseRow(response.Columns, values)
+				response.Rows = append(response.Rows, row)
+			}
+		case frameComplete:
+			d.uint16()
+			response.RowsAffected = int64(d.uint64())
+			if d.err != nil {
+				return nil, fmt.Errorf("Malformed complete frame: %w", d.err)
+			}
+			return response, nil
+		case frameError:
+			e := &engine.Error{Status: d.uint16(), Statement: d.text(), Position: int(d.int32())}
+			if cause := d.text(); cause != "" {
+				e.Err = errors.New(cause)
+			}
+			if d.err != nil {
+				return nil, fmt.Errorf("Malformed error frame: %w", d.err)
+			}
+			return nil, e
+		default:
+			return nil, fmt.Errorf("Unexpected frame type %q", typ)
+		}
+
+		if d.err != nil {
+			return nil, fmt.Errorf("Malformed frame %q: %w", typ, d.err)
+		}
+	}
+}
+
+// Close ends the session.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the server drops the connection anyway, so the error doesn't matter
+	if writeFrame(c.w, frameTerminate, nil) == nil {
+		c.w.Flush()
+	}
+
+	return c.conn.Close()
+}
+
+func parseRow(columns []string, values []engine.Value) (*engine.Row, error) {
+	if len(values) != len(columns) || len(values) == 0 {
+		return nil, fmt.Errorf("Row has %d values, want %d", len(values), len(columns))
+	}
+
+	id := values[0]
+	if id.Type() != engine.ValueInteger || id.Int() < 0 || id.Int() > math.MaxUint32 {
+		return nil, fmt.Errorf("Invalid id %s", id)
+	}
+
+	return engine.NewRow(uint32(id.Int()), values[1:]...), nil
+}
@@ -0,0 +1,41 @@
+// Package server shares one storage between several clients over TCP, by the
+// protocol below, by the PostgreSQL one (see Server.ServePostgres) or by an
+// HTTP/JSON API (see Server.Handler).
+//
+// # Protocol
+//
+// Clients and the server exchange frames. A frame is a one byte type, the
+// length of the payload as a big endian uint32 and the payload:
+//
+//	+------+------------+---------------------+
+//	| type | length     | payload             |
+//	| 1 B  | 4 B        | length bytes        |
+//	+------+------------+---------------------+
+//
+// Payloads are built from these fields, all integers are big endian:
+//
+//	uint16, int32, uint64  fixed size integers
+//	text                   uint32 length followed by the bytes
+//	list                   uint32 count followed by that many texts
+//	value                  uint8 engine.ValueType followed by nothing for NULL,
+//	                       uint64 for INTEGER (two's complement) and REAL (IEEE
+//	                       754 bits), text for TEXT and BLOB
+//	values                 uint32 count followed by that many values
+//
+// A client sends a Query frame and reads frames until Complete or Error:
+//
+//	'Q' Query     text of the statement (the whole payload, no length)
+//	'T' Columns   list of column names, sent before the rows of a select
+//	'D' Row       values of a row
+//	'C' Complete  uint16 status, uint64 rows affected
+//	'E' Error     uint16 status, text statement, int32 position, text cause
+//
+// The statuses are the engine.ExecutionStatus values. Statement is the failed
+// statement, position is the byte offset of the failure in it or -1, and cause
+// is the message of the underlying error. Statement and cause may be empty.
+// Meta commands (`.exit`, `.dump`, ...) are not statements and fail with
+// engine.MetaUnrecognizedCommand.
+//
+// A client ends the session by closing the connection or sending an 'X'
+// Terminate frame with an empty payload.
+package server
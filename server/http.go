@@ -0,0 +1,222 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/output"
+)
+
+// TableName is the name of the only table, as listed by `GET /tables`.
+const TableName = engine.TableName
+
+// maxQuerySize limits the body of `POST /query`.
+const maxQuerySize = 1 << 20
+
+// queryResponse is the body of `POST /query`. Rows are objects keyed by
+// column, as printed by the json output mode.
+type queryResponse struct {
+	// Status is "ok", it's "error" in the body of failures.
+	Status       string          `json:"status"`
+	Columns      []string        `json:"columns,omitempty"`
+	Rows         json.RawMessage `json:"rows,omitempty"`
+	RowsAffected int64           `json:"rows_affected"`
+}
+
+type errorResponse struct {
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	Statement string `json:"statement,omitempty"`
+	Position  *int   `json:"position,omitempty"`
+}
+
+type table struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int      `json:"rows"`
+}
+
+// httpStatuses maps the statuses of failed statements to HTTP status codes,
+// 500 by default.
+var httpStatuses = map[engine.ExecutionStatus]int{
+	engine.PrepareUnrecognizedStatement: http.StatusBadRequest,
+	engine.PrepareSyntaxError:           http.StatusBadRequest,
+	engine.PrepareStringTooLong:         http.StatusBadRequest,
+	engine.PrepareNegativeId:            http.StatusBadRequest,
+	engine.MetaUnrecognizedCommand:      http.StatusBadRequest,
+	engine.ExecuteDuplicateKey:          http.StatusConflict,
+	engine.ExecuteNotNull:               http.StatusConflict,
+	engine.ExecuteTableFull:             http.StatusInsufficientStorage,
+	engine.ExecuteRowTooLarge:           http.StatusBadRequest,
+	engine.ExecuteReadOnly:              http.StatusForbidden,
+	engine.ExecuteLocked:                http.StatusLocked,
+	engine.TODO:                         http.StatusNotImplemented,
+}
+
+// Handler returns the HTTP API of the server:
+//
+//	POST /query   runs the statement of the body and returns its result
+//	GET  /tables  lists the tables with their columns and number of rows
+//	GET  /health  reports the server is up
+//
+// The body of `POST /query` is the statement itself, or with a JSON content
+// type, an object like {"query": "select"}. Meta commands are refused like
+// the other protocols. Responses are JSON with a status of "ok" or "error",
+// failures have the message and, if known, the statement and the position of
+// the error in it. The kind of a failure is told by the HTTP status code.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", s.handleQuery)
+	mux.HandleFunc("/tables", s.handleTables)
+	mux.HandleFunc("/health", s.handleHealth)
+
+	return mux
+}
+
+// ServeAPI accepts HTTP connections on l, serving Handler, until the server
+// is closed.
+func (s *Server) ServeAPI(l net.Listener) error {
+	hs := &http.Server{Handler: s.Handler()}
+	if !s.track(hs) {
+		l.Close()
+		return net.ErrClosed
+	}
+	defer s.untrack(hs)
+
+	err := hs.Serve(l)
+	if errors.Is(err, http.ErrServerClosed) {
+		return net.ErrClosed
+	}
+
+	return err
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	// a byte past the limit tells a body which is too large from other
+	// errors of reading it
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxQuerySize+1))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("Error reading the query: %w", err))
+		return
+	}
+	if len(body) > maxQuerySize {
+		writeHTTPError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("Query is larger than %d bytes", maxQuerySize))
+		return
+	}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		var request struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err)
+			return
+		}
+		body = []byte(request.Query)
+	}
+
+	result, err := s.exec(bytes.TrimSpace(body))
+	if err != nil {
+		writeEngineError(w, err)
+		return
+	}
+
+	response := queryResponse{Status: "ok", Columns: result.Columns, RowsAffected: result.RowsAffected}
+	if result.Columns != nil {
+		var rows []*engine.Row
+		for result.Next() {
+			rows = append(rows, result.Row())
+		}
+		var b bytes.Buffer
+		if err := writeJSONRows(&b, result.Columns, rows); err != nil {
+			writeHTTPError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response.Rows = b.Bytes()
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleTables(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	count, err := engine.CountRows(r.Context(), s.storage)
+	if err != nil {
+		writeEngineError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, []table{{Name: TableName, Columns: engine.ColumnNames(engine.SchemaOf(s.storage)), Rows: count}})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// allowMethod writes an error and returns false if the request isn't of method.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s is not allowed", r.Method))
+
+	return false
+}
+
+func writeJSONRows(w io.Writer, columns []string, rows []*engine.Row) error {
+	jw, err := output.New(output.ModeJSON, w, true)
+	if err != nil {
+		return err
+	}
+
+	return jw.WriteRows(columns, rows)
+}
+
+// writeEngineError writes err by the HTTP status code of its status.
+func writeEngineError(w http.ResponseWriter, err error) {
+	code, ok := httpStatuses[engine.StatusOf(err)]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+
+	writeHTTPError(w, code, err)
+}
+
+func writeHTTPError(w http.ResponseWriter, code int, err error) {
+	response := errorResponse{Status: "error", Error: err.Error()}
+
+	var e *engine.Error
+	if errors.As(err, &e) && e.Statement != "" {
+		response.Statement = e.Statement
+		if e.Position >= 0 {
+			response.Position = &e.Position
+		}
+	}
+
+	writeJSON(w, code, response)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
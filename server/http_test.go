@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/btree"
+)
+
+func TestHandler(t *testing.T) {
+	table, err := btree.DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	ts := httptest.NewServer(New(table).Handler())
+	defer ts.Close()
+
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantCode    int
+		want        string
+	}{
+		{"POST", "/query", "text/plain", "insert 1 user1 person1@example.com", 200, `{"status":"ok","rows_affected":1}`},
+		{"POST", "/query", "application/json", `{"query": "insert 2 user2 person2@example.com"}`, 200, `{"status":"ok","rows_affected":1}`},
+		{"POST", "/query", "text/plain", "select", 200, `{"status":"ok","columns":["id","username","email"],"rows":[{"id":1,"username":"user1","email":"person1@example.com"},{"id":2,"username":"user2","email":"person2@example.com"}],"rows_affected":0}`},
+		{"POST", "/query", "text/plain", "insert 1 user1 person1@example.com", 409, `{"status":"error","error":"Duplicate key in ` + "`insert 1 user1 person1@example.com`" + `","statement":"insert 1 user1 person1@example.com"}`},
+		{"POST", "/query", "text/plain", "insert 3", 400, `{"status":"error","error":"Syntax error at position 8 of ` + "`insert 3`" + `","statement":"insert 3","position":8}`},
+		{"POST", "/query", "text/plain", ".exit", 400, `{"status":"error","error":"Unrecognized command in ` + "`.exit`" + `","statement":".exit"}`},
+		{"POST", "/query", "application/json", `{"query":`, 400, ""},
+		{"POST", "/query", "text/plain", strings.Repeat(" ", maxQuerySize+1), 413, `{"status":"error","error":"Query is larger than 1048576 bytes"}`},
+		{"GET", "/query", "", "", 405, ""},
+		{"GET", "/tables", "", "", 200, `[{"name":"users","columns":["id","username","email"],"rows":2}]`},
+		{"GET", "/health", "", "", 200, `{"status":"ok"}`},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", tt.contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s %s %q body error = %v", tt.method, tt.path, tt.body, err)
+		}
+
+		if resp.StatusCode != tt.wantCode {
+			t.Errorf("%s %s %q code = %d, want %d", tt.method, tt.path, tt.body, resp.StatusCode, tt.wantCode)
+		}
+		if tt.want != "" && compact(t, got) != tt.want {
+			t.Errorf("%s %s %q = %s, want %s", tt.method, tt.path, tt.body, compact(t, got), tt.want)
+		}
+	}
+}
+
+// errReader fails every read, like a client which resets the connection.
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
+
+func TestHandlerReadError(t *testing.T) {
+	table, err := btree.DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+
+	// only a body which is too large is 413
+	w := httptest.NewRecorder()
+	New(table).Handler().ServeHTTP(w, httptest.NewRequest("POST", "/query", errReader{}))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "connection reset by peer") {
+		t.Errorf("POST /query with a failing body = %d %s, want %d", w.Code, w.Body, http.StatusBadRequest)
+	}
+}
+
+func TestHandlerMemoryStorage(t *testing.T) {
+	table, err := btree.DbOpen(engine.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	ts := httptest.NewServer(New(table).Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/query", "text/plain", strings.NewReader("insert 1 user1 person1@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /query code = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp, err = http.Get(ts.URL + "/tables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got json.RawMessage
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"name":"users","columns":["id","username","email"],"rows":1}]`; compact(t, got) != want {
+		t.Errorf("GET /tables = %s, want %s", compact(t, got), want)
+	}
+}
+
+func TestHandlerTablesInternalNode(t *testing.T) {
+	table, err := btree.DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	// the 8th row splits the root leaf, which can't be counted yet
+	for i := 1; i <= 8; i++ {
+		if err := table.Insert(engine.NewRow(uint32(i), engine.NewText("user"), engine.NewText("person@example.com"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	New(table).Handler().ServeHTTP(w, httptest.NewRequest("GET", "/tables", nil))
+	if w.Code != http.StatusNotImplemented || !strings.Contains(w.Body.String(), "Need to implement searching an internal node") {
+		t.Errorf("GET /tables of a multi-leaf tree = %d %s, want %d", w.Code, w.Body, http.StatusNotImplemented)
+	}
+}
+
+func compact(t *testing.T, raw json.RawMessage) string {
+	t.Helper()
+
+	var b strings.Builder
+	if err := json.NewEncoder(&b).Encode(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func TestServeAPI(t *testing.T) {
+	addr := startServer(t, (*Server).ServeAPI)
+
+	resp, err := http.Get("http://" + addr + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /health code = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,353 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// The codes of the startup packets of the PostgreSQL protocol.
+const (
+	pgProtocolVersion = 196608 // 3.0
+	pgCancelRequest   = 80877102
+	pgSSLRequest      = 80877103
+	pgGSSENCRequest   = 80877104
+)
+
+// sqlStates maps the statuses to the SQLSTATE codes of PostgreSQL.
+var sqlStates = map[engine.ExecutionStatus]string{
+	engine.PrepareUnrecognizedStatement: "42601", // syntax_error
+	engine.PrepareSyntaxError:           "42601", // syntax_error
+	engine.PrepareStringTooLong:         "22001", // string_data_right_truncation
+	engine.PrepareNegativeId:            "22003", // numeric_value_out_of_range
+	engine.ExecuteTableFull:             "53100", // disk_full
+	engine.ExecuteTableEmpty:            "02000", // no_data
+	engine.ExecuteRowNotFound:           "XX001", // data_corrupted
+	engine.ExecutePageFetchError:        "58030", // io_error
+	engine.ExecuteDuplicateKey:          "23505", // unique_violation
+	engine.ExecuteNotNull:               "23502", // not_null_violation
+	engine.ExecuteCorrupt:               "XX001", // data_corrupted
+	engine.ExecuteRowTooLarge:           "54000", // program_limit_exceeded
+	engine.ExecuteLocked:                "55P03", // lock_not_available
+	engine.ExecuteReadOnly:              "25006", // read_only_sql_transaction
+	engine.MetaUnrecognizedCommand:      "0A000", // feature_not_supported
+	engine.MetaCommandFailure:           "58000", // system_error
+	engine.TODO:                         "0A000", // feature_not_supported
+}
+
+// sqlState returns the SQLSTATE code of a status, internal_error by default.
+func sqlState(status engine.ExecutionStatus) string {
+	if code, ok := sqlStates[status]; ok {
+		return code
+	}
+
+	return "XX000"
+}
+
+// ServePostgres accepts connections on l until the server is closed, talking
+// the simple query flow of the PostgreSQL v3 protocol, so clients like psql
+// can be used. Authentication is not supported and the extended query flow
+// (e.g. the default of pgx, see its simple_protocol mode) fails.
+//
+// A query may have several statements separated by `;`. Selects are sent
+// with an int8 id and text username and email.
+func (s *Server) ServePostgres(l net.Listener) error {
+	return s.serve(l, s.servePostgres)
+}
+
+func (s *Server) servePostgres(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	if ok, err := pgStartup(r, w); !ok || err != nil {
+		return err
+	}
+
+	// after a failure of the extended query flow, messages are discarded up
+	// to the next Sync
+	failed := false
+	for {
+		typ, payload, err := readPGMessage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		switch typ {
+		case 'Q':
+			query, _, _ := strings.Cut(string(payload), "\x00")
+			if err := s.pgQuery(w, query); err != nil {
+				return err
+			}
+		case 'X':
+			return nil
+		case 'S':
+			failed = false
+			if err := writePGReady(w); err != nil {
+				return err
+			}
+		case 'H':
+			// Flush, written below
+		case 'P', 'B', 'D', 'E', 'C', 'F':
+			if !failed {
+				failed = true
+				err := writePGError(w, "0A000", "Extended query protocol is not supported, use simple queries", 0)
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("Unexpected message type %q", typ)
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// pgStartup negotiates a session. The first result is false if the client
+// doesn't want to run queries, e.g. it's a cancel request.
+func pgStartup(r *bufio.Reader, w *bufio.Writer) (bool, error) {
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return false, err
+		}
+		if size < 8 || size > 10000 {
+			return false, fmt.Errorf("Invalid startup packet length %d", size)
+		}
+		payload := make([]byte, size-4)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return false, err
+		}
+
+		switch code := binary.BigEndian.Uint32(payload); code {
+		case pgSSLRequest, pgGSSENCRequest:
+			// encryption is not supported, the client may go on in plain text
+			if err := w.WriteByte('N'); err != nil {
+				return false, err
+			}
+			if err := w.Flush(); err != nil {
+				return false, err
+			}
+		case pgCancelRequest:
+			return false, nil
+		case pgProtocolVersion:
+			var e encoder
+			e.int32(0) // AuthenticationOk
+			writePGMessage(w, 'R', e.Bytes())
+			for _, p := range [][2]string{
+				{"server_version", "14.0 (sqltut)"},
+				{"server_encoding", "UTF8"},
+				{"client_encoding", "UTF8"},
+				{"DateStyle", "ISO, MDY"},
+				{"integer_datetimes", "on"},
+				{"standard_conforming_strings", "on"},
+			} {
+				var e encoder
+				e.cstring(p[0])
+				e.cstring(p[1])
+				writePGMessage(w, 'S', e.Bytes())
+			}
+			var key encoder
+			key.int32(0) // process ID
+			key.int32(0) // secret key
+			writePGMessage(w, 'K', key.Bytes())
+			if err := writePGReady(w); err != nil {
+				return false, err
+			}
+
+			return true, w.Flush()
+		default:
+			writePGError(w, "08P01", fmt.Sprintf("Unsupported frontend protocol %d.%d", code>>16, code&0xFFFF), 0)
+			return false, w.Flush()
+		}
+	}
+}
+
+// pgQuery runs the statements of a query up to the first failure.
+func (s *Server) pgQuery(w *bufio.Writer, query string) error {
+	empty := true
+	offset := 0
+	for _, statement := range strings.Split(query, ";") {
+		start := offset + len(statement) - len(strings.TrimLeft(statement, " \t\r\n"))
+		offset += len(statement) + 1
+		statement = strings.TrimSpace(statement)
+		if statement == "" {
+			continue
+		}
+		empty = false
+
+		result, err := s.exec([]byte(statement))
+		if err != nil {
+			if err := writePGExecError(w, err, query[:start]); err != nil {
+				return err
+			}
+			break
+		}
+		if err := writePGResult(w, result); err != nil {
+			return err
+		}
+	}
+
+	if empty {
+		writePGMessage(w, 'I', nil) // EmptyQueryResponse
+	}
+
+	return writePGReady(w)
+}
+
+func writePGResult(w *bufio.Writer, result *engine.Result) error {
+	if result.Columns == nil {
+		var e encoder
+		e.cstring("INSERT 0 " + strconv.FormatInt(result.RowsAffected, 10))
+		return writePGMessage(w, 'C', e.Bytes())
+	}
+
+	var desc encoder
+	desc.int16(int16(len(result.Columns)))
+	for i, column := range result.Columns {
+		oid, size := pgTypeOf(i)
+		desc.cstring(column)
+		desc.int32(0) // table OID
+		desc.int16(0) // column number
+		desc.int32(oid)
+		desc.int16(size)
+		desc.int32(-1) // type modifier
+		desc.int16(0)  // text format
+	}
+	if err := writePGMessage(w, 'T', desc.Bytes()); err != nil {
+		return err
+	}
+
+	var count int
+	for ; result.Next(); count++ {
+		values := result.Row().Values()
+		var e encoder
+		e.int16(int16(len(values)))
+		for _, v := range values {
+			if v.IsNull() {
+				e.int32(-1)
+				continue
+			}
+			text := v.String()
+			e.int32(int32(len(text)))
+			e.WriteString(text)
+		}
+		if err := writePGMessage(w, 'D', e.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	var e encoder
+	e.cstring("SELECT " + strconv.Itoa(count))
+
+	return writePGMessage(w, 'C', e.Bytes())
+}
+
+// pgTypeOf returns the OID and the size of the PostgreSQL type of a column.
+// Only the id has a type, the other columns hold values of any type which are
+// sent as text.
+func pgTypeOf(column int) (int32, int16) {
+	if column == 0 {
+		return 20, 8 // int8, the range of uint32 doesn't fit int4
+	}
+
+	return 25, -1 // text
+}
+
+// writePGExecError writes a failed statement which starts after prefix of the
+// query.
+func writePGExecError(w *bufio.Writer, err error, prefix string) error {
+	status, position := engine.ExitFailure, 0
+
+	var e *engine.Error
+	if errors.As(err, &e) {
+		status = e.Status
+		if e.Statement != "" && e.Position >= 0 && e.Position <= len(e.Statement) {
+			// positions of PostgreSQL count characters from 1
+			position = utf8.RuneCountInString(prefix) + utf8.RuneCountInString(e.Statement[:e.Position]) + 1
+		}
+	}
+
+	return writePGError(w, sqlState(status), err.Error(), position)
+}
+
+// writePGError writes an ErrorResponse. A position of 0 isn't sent.
+func writePGError(w *bufio.Writer, code, message string, position int) error {
+	var e encoder
+	for _, field := range []struct {
+		typ   byte
+		value string
+	}{
+		{'S', "ERROR"},
+		{'V', "ERROR"},
+		{'C', code},
+		{'M', message},
+	} {
+		e.WriteByte(field.typ)
+		e.cstring(field.value)
+	}
+	if position > 0 {
+		e.WriteByte('P')
+		e.cstring(strconv.Itoa(position))
+	}
+	e.WriteByte(0)
+
+	return writePGMessage(w, 'E', e.Bytes())
+}
+
+func writePGReady(w *bufio.Writer) error {
+	return writePGMessage(w, 'Z', []byte{'I'})
+}
+
+// writePGMessage writes a message, its length counts itself but not the type.
+func writePGMessage(w *bufio.Writer, typ byte, payload []byte) error {
+	var header [5]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)+4))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+
+	return err
+}
+
+func readPGMessage(r *bufio.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size < 4 || size-4 > maxFrameSize {
+		return 0, nil, fmt.Errorf("Invalid message length %d", size)
+	}
+	payload := make([]byte, size-4)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
+
+func (e *encoder) int16(v int16) {
+	binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *encoder) cstring(v string) {
+	e.WriteString(v)
+	e.WriteByte(0)
+}
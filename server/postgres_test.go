@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pgMessage is a message of the server with the fields of the interesting
+// ones decoded.
+type pgMessage struct {
+	typ    byte
+	fields []string
+}
+
+// pgQuery sends the startup packet when query is empty and a Query message
+// otherwise, and returns the messages up to ReadyForQuery.
+func pgQuery(t *testing.T, conn net.Conn, r *bufio.Reader, query string) []pgMessage {
+	t.Helper()
+
+	var e encoder
+	if query == "" {
+		e.int32(pgProtocolVersion)
+		e.cstring("user")
+		e.cstring("test")
+		e.WriteByte(0)
+		binary.Write(conn, binary.BigEndian, int32(e.Len()+4))
+	} else {
+		e.cstring(query)
+		conn.Write([]byte{'Q'})
+		binary.Write(conn, binary.BigEndian, int32(e.Len()+4))
+	}
+	conn.Write(e.Bytes())
+
+	var messages []pgMessage
+	for {
+		typ, payload, err := readPGMessage(r)
+		if err != nil {
+			t.Fatalf("readPGMessage() error = %v", err)
+		}
+		m := pgMessage{typ: typ}
+		switch typ {
+		case 'C':
+			m.fields = []string{strings.TrimSuffix(string(payload), "\x00")}
+		case 'D':
+			d := newDecoder(payload[2:])
+			for i := binary.BigEndian.Uint16(payload); i > 0; i-- {
+				m.fields = append(m.fields, d.text())
+			}
+		case 'E':
+			// keep the code and the position
+			for _, f := range bytes.Split(payload, []byte{0}) {
+				if len(f) > 0 && (f[0] == 'C' || f[0] == 'P') {
+					m.fields = append(m.fields, string(f))
+				}
+			}
+		}
+		messages = append(messages, m)
+		if typ == 'Z' {
+			return messages
+		}
+	}
+}
+
+func types(messages []pgMessage) string {
+	var b strings.Builder
+	for _, m := range messages {
+		b.WriteByte(m.typ)
+	}
+
+	return b.String()
+}
+
+func TestServePostgres(t *testing.T) {
+	addr := startServer(t, (*Server).ServePostgres)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	if got := types(pgQuery(t, conn, r, "")); got != "RSSSSSSKZ" {
+		t.Fatalf("startup messages = %q, want %q", got, "RSSSSSSKZ")
+	}
+
+	tests := []struct {
+		query string
+		want  []pgMessage
+	}{
+		{"insert 1 user1 person1@example.com;", []pgMessage{
+			{'C', []string{"INSERT 0 1"}}, {'Z', nil},
+		}},
+		{"insert 2 user2 person2@example.com; select", []pgMessage{
+			{'C', []string{"INSERT 0 1"}},
+			{'T', nil},
+			{'D', []string{"1", "user1", "person1@example.com"}},
+			{'D', []string{"2", "user2", "person2@example.com"}},
+			{'C', []string{"SELECT 2"}},
+			{'Z', nil},
+		}},
+		{"insert 1 user1 person1@example.com; select", []pgMessage{
+			{'E', []string{"C23505"}}, {'Z', nil},
+		}},
+		{"select; insert 3 user3", []pgMessage{
+			{'T', nil},
+			{'D', []string{"1", "user1", "person1@example.com"}},
+			{'D', []string{"2", "user2", "person2@example.com"}},
+			{'C', []string{"SELECT 2"}},
+			{'E', []string{"C42601", "P23"}},
+			{'Z', nil},
+		}},
+		{".exit", []pgMessage{{'E', []string{"C0A000"}}, {'Z', nil}}},
+		{" ; ", []pgMessage{{'I', nil}, {'Z', nil}}},
+	}
+	for _, tt := range tests {
+		if got := pgQuery(t, conn, r, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("query %q = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// the session ends with a Terminate message
+	conn.Write([]byte{'X', 0, 0, 0, 4})
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("ReadByte() after Terminate error = %v, want %v", err, io.EOF)
+	}
+}
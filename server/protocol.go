@@ -0,0 +1,215 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+const (
+	frameQuery     byte = 'Q'
+	frameColumns   byte = 'T'
+	frameRow       byte = 'D'
+	frameComplete  byte = 'C'
+	frameError     byte = 'E'
+	frameTerminate byte = 'X'
+)
+
+// maxFrameSize protects both sides from allocating huge payloads.
+const maxFrameSize = 16 << 20
+
+func writeFrame(w *bufio.Writer, typ byte, payload []byte) error {
+	var header [5]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+
+	return err
+}
+
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxFrameSize {
+		return 0, nil, fmt.Errorf("Frame of %d bytes is larger than %d", size, maxFrameSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], payload, nil
+}
+
+// encoder builds the payload of a frame.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) uint16(v uint16) {
+	binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *encoder) int32(v int32) {
+	binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *encoder) uint64(v uint64) {
+	binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *encoder) text(v string) {
+	binary.Write(&e.Buffer, binary.BigEndian, uint32(len(v)))
+	e.WriteString(v)
+}
+
+func (e *encoder) list(values []string) {
+	binary.Write(&e.Buffer, binary.BigEndian, uint32(len(values)))
+	for _, v := range values {
+		e.text(v)
+	}
+}
+
+func (e *encoder) value(v engine.Value) {
+	e.WriteByte(byte(v.Type()))
+	switch v.Type() {
+	case engine.ValueInteger:
+		e.uint64(uint64(v.Int()))
+	case engine.ValueReal:
+		e.uint64(math.Float64bits(v.Float()))
+	case engine.ValueText, engine.ValueBlob:
+		e.text(v.Text())
+	}
+}
+
+func (e *encoder) values(values []engine.Value) {
+	binary.Write(&e.Buffer, binary.BigEndian, uint32(len(values)))
+	for _, v := range values {
+		e.value(v)
+	}
+}
+
+// decoder reads the fields of a payload. The first error is kept and makes
+// the following reads no-ops.
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func newDecoder(payload []byte) *decoder {
+	return &decoder{r: bytes.NewReader(payload)}
+}
+
+func (d *decoder) read(v interface{}) {
+	if d.err == nil {
+		d.err = binary.Read(d.r, binary.BigEndian, v)
+	}
+}
+
+func (d *decoder) uint16() (v uint16) {
+	d.read(&v)
+	return v
+}
+
+func (d *decoder) int32() (v int32) {
+	d.read(&v)
+	return v
+}
+
+func (d *decoder) uint64() (v uint64) {
+	d.read(&v)
+	return v
+}
+
+func (d *decoder) text() string {
+	var size uint32
+	d.read(&size)
+	if d.err != nil {
+		return ""
+	}
+	if int64(size) > int64(d.r.Len()) {
+		d.err = io.ErrUnexpectedEOF
+		return ""
+	}
+
+	b := make([]byte, size)
+	_, d.err = io.ReadFull(d.r, b)
+
+	return string(b)
+}
+
+func (d *decoder) list() []string {
+	var count uint32
+	d.read(&count)
+	if d.err != nil {
+		return nil
+	}
+	// every text has at least its length
+	if int64(count)*4 > int64(d.r.Len()) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+
+	values := make([]string, count)
+	for i := range values {
+		values[i] = d.text()
+	}
+
+	return values
+}
+
+func (d *decoder) value() engine.Value {
+	var typ uint8
+	d.read(&typ)
+	if d.err != nil {
+		return engine.Null
+	}
+
+	switch engine.ValueType(typ) {
+	case engine.ValueNull:
+		return engine.Null
+	case engine.ValueInteger:
+		return engine.NewInteger(int64(d.uint64()))
+	case engine.ValueReal:
+		return engine.NewReal(math.Float64frombits(d.uint64()))
+	case engine.ValueText:
+		return engine.NewText(d.text())
+	case engine.ValueBlob:
+		return engine.NewBlob([]byte(d.text()))
+	}
+	d.err = fmt.Errorf("Unknown value type %d", typ)
+
+	return engine.Null
+}
+
+func (d *decoder) values() []engine.Value {
+	var count uint32
+	d.read(&count)
+	if d.err != nil {
+		return nil
+	}
+	// every value has at least its type
+	if int64(count) > int64(d.r.Len()) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+
+	values := make([]engine.Value, count)
+	for i := range values {
+		values[i] = d.value()
+	}
+
+	return values
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+// Server runs the statements of its clients against one storage.
+type Server struct {
+	storage engine.Storage
+
+	mu sync.Mutex
+	// closers are the listeners, connections and HTTP servers to close
+	closers map[io.Closer]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// New returns a server for storage. The storage is owned by the caller and
+// isn't closed by the server.
+func New(storage engine.Storage) *Server {
+	return &Server{
+		storage: storage,
+		closers: make(map[io.Closer]struct{}),
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves its connections.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until the server is closed.
+func (s *Server) Serve(l net.Listener) error {
+	return s.serve(l, s.serveConn)
+}
+
+func (s *Server) serve(l net.Listener, handle func(net.Conn) error) error {
+	if !s.track(l) {
+		return net.ErrClosed
+	}
+	defer s.untrack(l)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return net.ErrClosed
+			}
+			return err
+		}
+		if !s.track(conn) {
+			return net.ErrClosed
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			if err := handle(conn); err != nil && !s.isClosed() {
+				log.Printf("Connection from %s: %s", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// Close stops the listeners, closes the connections and waits for their
+// statements to finish.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.closers {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// track keeps c to be closed by Close. If the server is already closed, c is
+// closed right away and false is returned.
+func (s *Server) track(c io.Closer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		c.Close()
+		return false
+	}
+
+	s.closers[c] = struct{}{}
+
+	return true
+}
+
+// untrack closes c and forgets it.
+func (s *Server) untrack(c io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.closers, c)
+	c.Close()
+}
+
+func (s *Server) serveConn(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		typ, payload, err := readFrame(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		switch typ {
+		case frameQuery:
+			if err := s.query(w, payload); err != nil {
+				return err
+			}
+		case frameTerminate:
+			return nil
+		default:
+			return fmt.Errorf("Unexpected frame type %q", typ)
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// query runs a statement and writes its result. Only errors of the
+// connection are returned, failures of the statement are sent to the client.
+func (s *Server) query(w *bufio.Writer, command []byte) error {
+	result, err := s.exec(command)
+	if err != nil {
+		return writeError(w, err)
+	}
+
+	if result.Columns != nil {
+		var e encoder
+		e.list(result.Columns)
+		if err := writeFrame(w, frameColumns, e.Bytes()); err != nil {
+			return err
+		}
+
+		for result.Next() {
+			var e encoder
+			e.values(result.Row().Values())
+			if err := writeFrame(w, frameRow, e.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+
+	var e encoder
+	e.uint16(engine.ExecuteSuccess)
+	e.uint64(uint64(result.RowsAffected))
+
+	return writeFrame(w, frameComplete, e.Bytes())
+}
+
+// exec runs a statement of a client.
+func (s *Server) exec(command []byte) (*engine.Result, error) {
+	// meta commands act on the process, e.g. `.exit`, so clients can't run them
+	if bytes.HasPrefix(bytes.TrimSpace(command), []byte(".")) {
+		return nil, &engine.Error{Status: engine.MetaUnrecognizedCommand, Statement: string(command), Position: -1}
+	}
+
+	return engine.Exec(context.Background(), command, s.storage)
+}
+
+func writeError(w *bufio.Writer, err error) error {
+	e := &engine.Error{Status: engine.ExitFailure, Position: -1, Err: err}
+	errors.As(err, &e)
+
+	cause := ""
+	if e.Err != nil {
+		cause = e.Err.Error()
+	}
+
+	var enc encoder
+	enc.uint16(e.Status)
+	enc.text(e.Statement)
+	enc.int32(int32(e.Position))
+	enc.text(cause)
+
+	return writeFrame(w, frameError, enc.Bytes())
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+	"github.com/meysampg/sqltut/engine/storage/btree"
+)
+
+func startServer(t *testing.T, serve func(*Server, net.Listener) error) string {
+	t.Helper()
+
+	table, err := btree.DbOpen(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serveTable(t, table, serve)
+}
+
+// serveTable serves table on a new local listener until the test is cleaned
+// up, and then closes table.
+func serveTable(t *testing.T, table engine.Storage, serve func(*Server, net.Listener) error) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(table)
+	done := make(chan error)
+	go func() { done <- serve(s, l) }()
+	t.Cleanup(func() {
+		s.Close()
+		if err := <-done; !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Serve() error = %v, want %v", err, net.ErrClosed)
+		}
+		table.Close()
+	})
+
+	return l.Addr().String()
+}
+
+func dial(t *testing.T, addr string) *Client {
+	t.Helper()
+
+	c, err := Dial(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c
+}
+
+func TestServer(t *testing.T) {
+	addr := startServer(t, (*Server).Serve)
+	c := dial(t, addr)
+
+	for _, command := range []string{"insert 1 user1 person1@example.com", "insert 2 -1.5 x'00ff'"} {
+		response, err := c.Exec([]byte(command))
+		if err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+		if response.RowsAffected != 1 || response.Columns != nil {
+			t.Errorf("Exec() = %+v, want 1 row affected and no columns", response)
+		}
+	}
+
+	tests := []struct {
+		command string
+		want    error
+		pos     int
+	}{
+		{"insert 1 user1 person1@example.com", engine.ErrDuplicateKey, -1},
+		{"insert 2 user2", engine.ErrSyntax, 14},
+		{"update", engine.ErrUnrecognizedStatement, 0},
+		{".exit", engine.ErrUnrecognizedCommand, -1},
+	}
+	for _, tt := range tests {
+		_, err := c.Exec([]byte(tt.command))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Exec(%q) error = %v, want %v", tt.command, err, tt.want)
+			continue
+		}
+		var e *engine.Error
+		if errors.As(err, &e); e.Statement != tt.command || e.Position != tt.pos {
+			t.Errorf("Exec(%q) error at %q:%d, want %q:%d", tt.command, e.Statement, e.Position, tt.command, tt.pos)
+		}
+	}
+
+	// another client sees the rows of the first one
+	response, err := dial(t, addr).Exec([]byte("select"))
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	want := []*engine.Row{
+		engine.NewRow(1, engine.NewText("user1"), engine.NewText("person1@example.com")),
+		engine.NewRow(2, engine.NewReal(-1.5), engine.NewBlob([]byte{0, 0xff})),
+	}
+	if !reflect.DeepEqual(response.Columns, engine.Columns) || !reflect.DeepEqual(response.Rows, want) {
+		t.Errorf("Exec() = %v %v, want %v %v", response.Columns, response.Rows, engine.Columns, want)
+	}
+}
+
+func TestServerMemoryStorage(t *testing.T) {
+	table, err := btree.DbOpen(engine.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serveTable(t, table, (*Server).Serve)
+
+	// clients share the DB in memory of the server
+	if _, err := dial(t, addr).Exec([]byte("insert 1 user1 person1@example.com")); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	response, err := dial(t, addr).Exec([]byte("select"))
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	want := []*engine.Row{engine.NewRow(1, engine.NewText("user1"), engine.NewText("person1@example.com"))}
+	if !reflect.DeepEqual(response.Rows, want) {
+		t.Errorf("Exec(select) = %v, want %v", response.Rows, want)
+	}
+}
+func TestServerConcurrentClients(t *testing.T) {
+	addr := startServer(t, (*Server).Serve)
+
+	// dial fails the test, so clients are dialed before the goroutines
+	clients := make([]*Client, 4)
+	for i := range clients {
+		clients[i] = dial(t, addr)
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				if _, err := c.Exec([]byte("select")); err != nil {
+					t.Errorf("Exec() error = %v", err)
+				}
+			}
+			if _, err := c.Exec([]byte("insert " + string(rune('1'+i)) + " user person@example.com")); err != nil {
+				t.Errorf("Exec() error = %v", err)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	response, err := dial(t, addr).Exec([]byte("select"))
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if len(response.Rows) != 4 {
+		t.Errorf("Exec() = %d rows, want 4", len(response.Rows))
+	}
+}
@@ -0,0 +1,145 @@
+// Package sqltut embeds the database into Go programs.
+//
+//	db, err := sqltut.Open("./db", &sqltut.Options{Engine: "btree"})
+//	_, err = db.Exec(ctx, "insert ? ? ?", 1, "user1", "person1@example.com")
+//	rows, err := db.Query(ctx, "select")
+//
+// Every method takes a context, so long scans are cancelled as soon as the
+// context is done.
+package sqltut
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/meysampg/sqltut/engine"
+	_ "github.com/meysampg/sqltut/engine/storage/arraylike"
+	_ "github.com/meysampg/sqltut/engine/storage/btree"
+)
+
+var (
+	// ErrClosed is returned by methods of a closed DB.
+	ErrClosed = errors.New("sqltut: database is closed")
+	// ErrNoRows is returned by Row.Scan when the query returns no rows.
+	ErrNoRows = errors.New("sqltut: no rows in result set")
+)
+
+// Options configure how a DB file is opened.
+type Options struct {
+	// Engine is the registered storage engine of the DB file, arraylike
+	// (default) or btree.
+	Engine string
+	// ReadOnly opens the DB file with a shared lock, so other processes can
+	// read it at the same time.
+	ReadOnly bool
+	// BusyTimeout is how long to wait for the lock of another process on the
+	// DB file before failing with engine.ErrLocked.
+	BusyTimeout time.Duration
+	// MMap reads the DB file through a memory mapping, on engines which
+	// support it like btree.
+	MMap bool
+}
+
+// DB is an open DB file. It's safe for concurrent use; reads run concurrently
+// and writes are serialized by the storage.
+type DB struct {
+	storage engine.Storage
+	// mu guards closed, statements hold a read lock while they're running.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NamedArg binds a value to the `:name` placeholder of a statement.
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Named returns an argument for the `:name` placeholder.
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// Open opens the DB file at path. opts may be nil.
+func Open(path string, opts *Options) (*DB, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	storage, err := engine.OpenStorage(opts.Engine, path, engine.Options{ReadOnly: opts.ReadOnly, BusyTimeout: opts.BusyTimeout, MMap: opts.MMap})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{storage: storage}, nil
+}
+
+// Exec executes a statement which doesn't return rows, like insert.
+func (db *DB) Exec(ctx context.Context, query string, args ...interface{}) (*engine.Result, error) {
+	return db.exec(ctx, query, args)
+}
+
+// Query executes a statement which returns rows, like select.
+func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	result, err := db.exec(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rows{ctx: ctx, result: result}, nil
+}
+
+// QueryRow executes a statement which is expected to return at most one row.
+// Errors are deferred until Row.Scan is called.
+func (db *DB) QueryRow(ctx context.Context, query string, args ...interface{}) *Row {
+	rows, err := db.Query(ctx, query, args...)
+
+	return &Row{rows: rows, err: err}
+}
+
+// Close flushes and closes the DB file.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+
+	return db.storage.Close()
+}
+
+func (db *DB) exec(ctx context.Context, query string, args []interface{}) (*engine.Result, error) {
+	statement, err := engine.PrepareStatement([]byte(query))
+	if err != nil {
+		return nil, err
+	}
+
+	// positional args are bound to the parameters which aren't named in
+	// order, whatever named args are between them
+	position := 0
+	for _, arg := range args {
+		if named, ok := arg.(NamedArg); ok {
+			err = statement.BindName(named.Name, named.Value)
+		} else {
+			position++
+			for statement.ParamName(position) != "" {
+				position++
+			}
+			err = statement.Bind(position, arg)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.closed {
+		return nil, ErrClosed
+	}
+
+	return statement.Exec(ctx, db.storage)
+}
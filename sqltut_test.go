@@ -0,0 +1,149 @@
+package sqltut
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/meysampg/sqltut/engine"
+)
+
+func TestDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path, &Options{Engine: "btree"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i, name := range []string{"user1", "user2"} {
+		if _, err := db.Exec(ctx, "insert ? :name ?", i+1, Named("name", name), name+"@example.com"); err != nil {
+			t.Fatalf("Exec() error = %v", err)
+		}
+	}
+	// positional args are counted apart from the named ones before them
+	if _, err := db.Exec(ctx, "insert ? :name ?", Named("name", "user3"), 3, "user3@example.com"); err != nil {
+		t.Fatalf("Exec() with the named arg first error = %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "select"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Exec() on closed db error = %v, want %v", err, ErrClosed)
+	}
+
+	db, err = Open(path, &Options{Engine: "btree"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var id int
+	var username string
+	var email []byte
+	if err := db.QueryRow(ctx, "select").Scan(&id, &username, &email); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if id != 1 || username != "user1" || string(email) != "user1@example.com" {
+		t.Errorf("QueryRow() = (%d, %s, %s), want (1, user1, user1@example.com)", id, username, email)
+	}
+
+	rows, err := db.Query(ctx, "select")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for rows.Next() {
+		var values [3]interface{}
+		if err := rows.Scan(&values[0], &values[1], &values[2]); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil || n != 3 {
+		t.Errorf("Query() returned %d rows and error %v, want 3 rows", n, err)
+	}
+	if err := db.QueryRow(ctx, "select where id = 3").Scan(&id, &username, &email); err != nil || username != "user3" || string(email) != "user3@example.com" {
+		t.Errorf("QueryRow(id = 3) = (%d, %s, %s), %v, want (3, user3, user3@example.com)", id, username, email, err)
+	}
+
+	var small int8
+	var text string
+	if err := db.QueryRow(ctx, "select").Scan(&text, &text, &text); err == nil {
+		t.Errorf("Scan() id into string, want error")
+	}
+	if err := db.QueryRow(ctx, "select").Scan(&small, &text, &text); err != nil {
+		t.Errorf("Scan() error = %v", err)
+	}
+}
+
+func TestDBContext(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := db.Exec(ctx, "insert 1 user1 person1@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(ctx, "insert 2 user2 person2@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(ctx, "select")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() {
+		t.Fatal("Next() = false, want a row")
+	}
+	cancel()
+	if rows.Next() {
+		t.Errorf("Next() after cancel = true, want false")
+	}
+	if !errors.Is(rows.Err(), context.Canceled) {
+		t.Errorf("Err() = %v, want %v", rows.Err(), context.Canceled)
+	}
+
+	if _, err := db.Query(ctx, "select"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Query() with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+	// the scan of the storage stops at the row it's cancelled on
+	if _, err := db.Query(&cancelAfter{Context: context.Background(), n: 2}, "select"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Query() cancelled while scanning error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := db.Exec(ctx, "insert 3 user3 person3@example.com"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Exec() with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+	if err := db.QueryRow(context.Background(), "select where id = 3").Scan(new(int), new(string), new(string)); !errors.Is(err, ErrNoRows) {
+		t.Errorf("QueryRow() of the row of a cancelled Exec error = %v, want %v", err, ErrNoRows)
+	}
+	if err := db.QueryRow(context.Background(), "select").Scan(new(int), new(string)); err == nil {
+		t.Errorf("Scan() with missing destination, want error")
+	}
+}
+
+// cancelAfter is a context which is cancelled once its error has been checked n
+// times, so it's cancelled in the middle of a scan.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+
+	return nil
+}
+
+func TestOpenUnknownEngine(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "test.db"), &Options{Engine: "nope"}); !errors.Is(err, engine.ErrEngineNotFound) {
+		t.Errorf("Open() with unknown engine error = %v, want %v", err, engine.ErrEngineNotFound)
+	}
+}